@@ -3,117 +3,258 @@ package main
 import (
 	"fmt"
 	"log"
+	"os"
 	"sort"
 	"strings"
 	"time"
-
-	"github.com/thiagokokada/hyprland-go/event"
 )
 
-func RunAnalysis(dbPath string, keywordsStr string, minDuration time.Duration, appOnly bool, timeRange string) {
-	var relatedKeywords []string
+// ParseKeywords splits a comma-separated keyword list into trimmed, lowercased keywords
+func ParseKeywords(keywordsStr string) []string {
+	var keywords []string
 	if keywordsStr != "" {
 		rawKeywords := strings.Split(keywordsStr, ",")
 		for _, kw := range rawKeywords {
 			trimmedKw := strings.TrimSpace(kw)
 			if trimmedKw != "" {
-				relatedKeywords = append(relatedKeywords, strings.ToLower(trimmedKw))
+				keywords = append(keywords, strings.ToLower(trimmedKw))
 			}
 		}
 	}
+	return keywords
+}
+
+// ResolveTimeRange returns the start of the analysis window ending at endTime,
+// together with a human readable description of it
+func ResolveTimeRange(timeRange string, endTime time.Time) (time.Time, string) {
+	switch timeRange {
+	case "day":
+		return endTime.AddDate(0, 0, -1), "the last 24 hours"
+	case "week":
+		return endTime.AddDate(0, 0, -7), "the last 7 days"
+	case "month":
+		return endTime.AddDate(0, -1, 0), "the last 30 days"
+	case "year":
+		return endTime.AddDate(-1, 0, 0), "the last year"
+	case "all":
+		return time.Time{}, "all available data"
+	default:
+		return endTime.AddDate(0, -1, 0), "the last 30 days" // Default to one month
+	}
+}
+
+// ResolveAnalysisRange returns the range covered by -time-range, which is either one
+// of the ranges understood by ResolveTimeRange ending now, or YYYY-MM-DD..YYYY-MM-DD
+func ResolveAnalysisRange(timeRange string, now time.Time) (time.Time, time.Time, string, error) {
+	if strings.Contains(timeRange, "..") {
+		startTime, endTime, err := ParseDateRange(timeRange)
+		if err != nil {
+			return time.Time{}, time.Time{}, "", err
+		}
+		lastDay := endTime.AddDate(0, 0, -1)
+		return startTime, endTime, fmt.Sprintf("%s to %s", startTime.Format("2006-01-02"), lastDay.Format("2006-01-02")), nil
+	}
+	startTime, description := ResolveTimeRange(timeRange, now)
+	return startTime, now, description, nil
+}
+
+func RunAnalysis(config AnalysisConfig) {
+	relatedKeywords := config.Keywords
 
 	if len(relatedKeywords) > 0 {
 		log.Printf("Filtering for related activities with keywords: [%s]", strings.Join(relatedKeywords, ", "))
 	}
-	if minDuration > 0 {
-		log.Printf("Filtering out activities shorter than %s", FormatDuration(minDuration))
+	if len(config.TagFilter) > 0 {
+		log.Printf("Filtering for activities tagged: [%s]", strings.Join(config.TagFilter, ", "))
+	}
+	if config.MinDuration > 0 && config.MinDurationPerVisit {
+		log.Printf("Filtering out visits shorter than %s", FormatDuration(config.MinDuration))
+	} else if config.MinDuration > 0 {
+		log.Printf("Filtering out activities shorter than %s", FormatDuration(config.MinDuration))
 	}
 	
-	log.Printf("Using database at %s", dbPath)
-	db, err := OpenDatabase(dbPath)
+	log.Printf("Using database at %s", config.DBPath)
+	db, err := OpenDatabase(config.DBPath)
 	if err != nil {
 		log.Fatalf("Error opening database: %v", err)
 	}
 	defer db.Close()
 	
 	// Calculate the time range based on the user's selection
-	endTime := time.Now()
-	var startTime time.Time
-	
-	switch timeRange {
-	case "day":
-		startTime = endTime.AddDate(0, 0, -1)
-		fmt.Println("Analyzing data from the last 24 hours")
-	case "week":
-		startTime = endTime.AddDate(0, 0, -7)
-		fmt.Println("Analyzing data from the last 7 days")
-	case "month":
-		startTime = endTime.AddDate(0, -1, 0)
-		fmt.Println("Analyzing data from the last 30 days")
-	case "year":
-		startTime = endTime.AddDate(-1, 0, 0)
-		fmt.Println("Analyzing data from the last year")
-	case "all":
-		startTime = time.Time{}
-		fmt.Println("Analyzing all available data")
+	startTime, endTime, description, err := ResolveAnalysisRange(config.TimeRange, time.Now())
+	if err != nil {
+		log.Fatalf("Error: %v", err)
+	}
+	message := "Analyzing data from " + description
+	if startTime.IsZero() {
+		message = "Analyzing " + description
+	}
+	switch {
+	case config.Report == "timeline" || config.Report == "calendar":
+		// These reports cover a fixed period rather than -time-range
+	case config.Format == FormatText:
+		fmt.Println(message)
 	default:
-		startTime = endTime.AddDate(0, -1, 0) // Default to one month
-		fmt.Println("Analyzing data from the last 30 days")
+		// Keep machine readable output clean
+		log.Println(message)
 	}
 	
-	generateSummaryReport(db, startTime, endTime, relatedKeywords, minDuration, appOnly)
+	if config.Compare != "" {
+		if config.Report != "summary" {
+			log.Fatalf("Error: -compare is only supported with the summary report")
+		}
+		generateComparisonReport(db, startTime, endTime, config)
+		return
+	}
+
+	switch config.Report {
+	case "pivot":
+		generatePivotReport(db, startTime, endTime, config)
+	case "timeline":
+		generateTimelineReport(db, config)
+	case "heatmap":
+		generateHeatmapReport(db, startTime, endTime, config)
+	case "calendar":
+		generateCalendarReport(db, config)
+	case "sessions":
+		generateSessionsReport(db, startTime, endTime, config)
+	case "focus":
+		generateFocusReport(db, startTime, endTime, config)
+	case "stats":
+		generateStatsReport(db, startTime, endTime, config)
+	case "tags":
+		generateTagsReport(db, startTime, endTime, config)
+	case "timesheet":
+		generateTimesheetReport(db, startTime, endTime, config)
+	case "goals":
+		generateGoalsReport(db, startTime, endTime, config)
+	case "breaks":
+		generateBreaksReport(db, startTime, endTime, config)
+	case "focus-sessions":
+		generateFocusSessionsReport(db, startTime, endTime, config)
+	default:
+		generateSummaryReport(db, startTime, endTime, config)
+	}
 }
 
-func generateSummaryReport(db *Database, startTime, endTime time.Time, relatedKeywords []string, minDuration time.Duration, appOnly bool) {
-	var appDurations map[string]time.Duration
-	var windowDurations map[string]time.Duration
-	var totalKeywordMatchDuration time.Duration
-	
-	if len(relatedKeywords) > 0 {
-		// Use the new database query for keyword filtering
-		summaries, err := db.GetKeywordFilteredSummary(startTime, endTime, relatedKeywords)
-		if err != nil {
-			log.Fatalf("Error retrieving keyword-filtered summary: %v", err)
-		}
-		
-		// Convert to map for compatibility with existing code
-		appDurations = make(map[string]time.Duration)
-		for _, summary := range summaries {
-			appDurations[summary.Name] = summary.Duration
-			totalKeywordMatchDuration += summary.Duration
-		}
-		
-		// Get window-level details if needed
-		if !appOnly {
-			entries, err := db.GetEvents(startTime, endTime)
-			if err != nil {
-				log.Fatalf("Error retrieving events from database: %v", err)
+// loadIntervals fetches the events and manual entries in the time range and returns the
+// intervals matching the keywords, without short visits when -min-duration applies per visit
+func loadIntervals(db *Database, startTime, endTime time.Time, config AnalysisConfig) []Interval {
+	intervals, err := db.GetIntervals(startTime, endTime, time.Time{})
+	if err != nil {
+		log.Fatalf("Error retrieving intervals from database: %v", err)
+	}
+	return config.FilterIntervals(intervals)
+}
+
+// loadEvents fetches the events in the time range, see GetEventsWithTag
+func loadEvents(db *Database, startTime, endTime time.Time) []LogEntry {
+	entries, err := db.GetEventsWithTag(startTime, endTime)
+	if err != nil {
+		log.Fatalf("Error retrieving events from database: %v", err)
+	}
+	return entries
+}
+
+// mergeManualEntries replaces the tracked time covered by manual entries with the entries
+func mergeManualEntries(db *Database, intervals []Interval, startTime, endTime time.Time) []Interval {
+	manual, err := db.GetManualEntries(startTime, endTime)
+	if err != nil {
+		log.Fatalf("Error retrieving manual entries from database: %v", err)
+	}
+	return ApplyManualEntries(intervals, manual, startTime, endTime)
+}
+
+// GetEventsWithTag fetches the events in the time range, preceded by a tag event for
+// the tag that was already active at startTime
+func (d *Database) GetEventsWithTag(startTime, endTime time.Time) ([]LogEntry, error) {
+	entries, err := d.GetEvents(startTime, endTime)
+	if err != nil || startTime.IsZero() {
+		return entries, err
+	}
+
+	tag, err := d.GetActiveTag(startTime)
+	if err != nil || tag == "" {
+		return entries, err
+	}
+	active := LogEntry{Timestamp: startTime, EventType: TagEventType}
+	active.EventData.Title = tag
+	return append([]LogEntry{active}, entries...), nil
+}
+
+// GetIntervals returns the focus intervals in the time range with the manual entries
+// merged in. If openEnd is non-zero, the interval still in progress ends at openEnd.
+func (d *Database) GetIntervals(startTime, endTime, openEnd time.Time) ([]Interval, error) {
+	entries, err := d.GetEventsWithTag(startTime, endTime)
+	if err != nil {
+		return nil, err
+	}
+	manual, err := d.GetManualEntries(startTime, endTime)
+	if err != nil {
+		return nil, err
+	}
+	return ApplyManualEntries(BuildIntervals(entries, openEnd), manual, startTime, endTime), nil
+}
+
+// FilterIntervals applies the keyword and tag filters and, with -per-visit, drops visits
+// shorter than -min-duration
+func (c AnalysisConfig) FilterIntervals(intervals []Interval) []Interval {
+	intervals = FilterIntervals(intervals, c.Keywords)
+	if len(c.TagFilter) > 0 {
+		var tagged []Interval
+		for _, iv := range intervals {
+			if iv.MatchesTags(c.TagFilter) {
+				tagged = append(tagged, iv)
 			}
-			
-			_, windowDurations, _ = CalculateDurations(entries, relatedKeywords)
-		}
-	} else {
-		// Use the optimized database query for application summary
-		summaries, err := db.GetApplicationSummary(startTime, endTime)
-		if err != nil {
-			log.Fatalf("Error retrieving application summary: %v", err)
 		}
-		
-		// Convert to map for compatibility with existing code
-		appDurations = make(map[string]time.Duration)
-		for _, summary := range summaries {
-			appDurations[summary.Name] = summary.Duration
+		intervals = tagged
+	}
+	if c.MinDurationPerVisit {
+		intervals = FilterShortVisits(intervals, c.MinDuration)
+	}
+	return intervals
+}
+
+// TotalMinDuration is the threshold applied to aggregated totals, which is
+// disabled when -min-duration already applied to individual visits
+func (c AnalysisConfig) TotalMinDuration() time.Duration {
+	if c.MinDurationPerVisit {
+		return 0
+	}
+	return c.MinDuration
+}
+
+func generateSummaryReport(db *Database, startTime, endTime time.Time, config AnalysisConfig) {
+	relatedKeywords := config.Keywords
+	minDuration := config.TotalMinDuration()
+	appOnly := config.AppOnly
+	format := config.Format
+
+	// Totals are computed from the reconstructed intervals so that idle time is excluded
+	// and manual entries replace the tracked time they overlap
+	intervals := loadIntervals(db, startTime, endTime, config)
+	appDurations := groupDurations(intervals, func(iv Interval) string { return iv.App })
+	windowDurations := groupDurations(intervals, Interval.WindowKey)
+	var totalKeywordMatchDuration time.Duration
+	for _, iv := range intervals {
+		totalKeywordMatchDuration += iv.Duration()
+	}
+
+	if format != FormatText {
+		appTitle, windowTitle := "Time Spent Per Application", "Time Spent Per Window"
+		if len(relatedKeywords) > 0 {
+			filter := fmt.Sprintf(" (Filtered by Keywords: [%s])", strings.Join(relatedKeywords, ", "))
+			appTitle += filter
+			windowTitle += filter
 		}
-		
-		// Get window-level details if needed
+		tables := []*ReportTable{SummaryTable(appTitle, "Application", appDurations, minDuration)}
 		if !appOnly {
-			entries, err := db.GetEvents(startTime, endTime)
-			if err != nil {
-				log.Fatalf("Error retrieving events from database: %v", err)
-			}
-			
-			_, windowDurations, _ = CalculateDurations(entries, relatedKeywords)
+			tables = append(tables, SummaryTable(windowTitle, "Window", windowDurations, minDuration))
+		}
+		if err := RenderTables(os.Stdout, format, tables...); err != nil {
+			log.Fatalf("Error writing report: %v", err)
 		}
+		return
 	}
 
 	if len(relatedKeywords) > 0 {
@@ -138,102 +279,29 @@ func generateSummaryReport(db *Database, startTime, endTime time.Time, relatedKe
 	}
 }
 
-func CalculateDurations(entries []LogEntry, relatedKeywords []string) (
-	appDurations map[string]time.Duration,
-	windowDurations map[string]time.Duration,
-	totalKeywordMatchDuration time.Duration,
-) {
-	appDurations = make(map[string]time.Duration)
-	windowDurations = make(map[string]time.Duration)
-
-	inIdlePeriod := false
-	
-	for i := 0; i < len(entries)-1; i++ {
-		current := entries[i]
-		next := entries[i+1]
-
-		// Skip if timestamps are out of order
-		if !next.Timestamp.After(current.Timestamp) {
-			log.Printf("Warning: Found out-of-order or duplicate timestamp at entry %d and %d. Skipping duration calculation for this interval.", i, i+1)
-			continue
-		}
-		
-		// Handle idle events
-		if current.EventType == "idle_start" {
-			inIdlePeriod = true
-			continue
-		}
-		
-		if current.EventType == "idle_end" {
-			inIdlePeriod = false
-			continue
-		}
-		
-		// Skip if we're in an idle period
-		if inIdlePeriod {
-			continue
-		}
-		
-		// Skip if the next entry is an idle_start (we'll handle this duration separately)
-		if next.EventType == "idle_start" {
-			duration := next.Timestamp.Sub(current.Timestamp)
-			
-			isMatch := false
-			if len(relatedKeywords) > 0 {
-				searchText := strings.ToLower(current.EventData.Name + " " + current.EventData.Title)
-				for _, keyword := range relatedKeywords {
-					if strings.Contains(searchText, keyword) {
-						isMatch = true
-						break
-					}
-				}
-			}
-
-			shouldProcessForSummaries := (len(relatedKeywords) == 0) || isMatch
-
-			if shouldProcessForSummaries {
-				appDurations[current.EventData.Name] += duration
-				windowKey := fmt.Sprintf("%s - %s", current.EventData.Name, current.EventData.Title)
-				windowDurations[windowKey] += duration
-			}
-
-			if len(relatedKeywords) > 0 && isMatch {
-				totalKeywordMatchDuration += duration
-			}
-			
-			continue
+// SortedSummary returns the durations at or above minDuration, longest first
+func SortedSummary(durations map[string]time.Duration, minDuration time.Duration) []TimeSummary {
+	summaryList := make([]TimeSummary, 0, len(durations))
+	for name, duration := range durations {
+		if duration >= minDuration {
+			summaryList = append(summaryList, TimeSummary{Name: name, Duration: duration})
 		}
-		
-		// Regular window change
-		if current.EventType == string(event.EventActiveWindow) && next.EventType == string(event.EventActiveWindow) {
-			duration := next.Timestamp.Sub(current.Timestamp)
-			
-			isMatch := false
-			if len(relatedKeywords) > 0 {
-				searchText := strings.ToLower(current.EventData.Name + " " + current.EventData.Title)
-				for _, keyword := range relatedKeywords {
-					if strings.Contains(searchText, keyword) {
-						isMatch = true
-						break
-					}
-				}
-			}
+	}
 
-			shouldProcessForSummaries := (len(relatedKeywords) == 0) || isMatch
+	sort.Slice(summaryList, func(i, j int) bool {
+		return summaryList[i].Duration > summaryList[j].Duration
+	})
 
-			if shouldProcessForSummaries {
-				appDurations[current.EventData.Name] += duration
-				windowKey := fmt.Sprintf("%s - %s", current.EventData.Name, current.EventData.Title)
-				windowDurations[windowKey] += duration
-			}
+	return summaryList
+}
 
-			if len(relatedKeywords) > 0 && isMatch {
-				totalKeywordMatchDuration += duration
-			}
-		}
+// SummaryTable converts a duration map into a two-column ReportTable
+func SummaryTable(title, nameHeader string, durations map[string]time.Duration, minDuration time.Duration) *ReportTable {
+	table := &ReportTable{Title: title, Columns: []string{nameHeader, "Duration"}}
+	for _, item := range SortedSummary(durations, minDuration) {
+		table.AddRow(item.Name, item.Duration)
 	}
-
-	return appDurations, windowDurations, totalKeywordMatchDuration
+	return table
 }
 
 func PrintSortedSummary(durations map[string]time.Duration, minDuration time.Duration) {
@@ -242,22 +310,12 @@ func PrintSortedSummary(durations map[string]time.Duration, minDuration time.Dur
 		return
 	}
 
-	summaryList := make([]TimeSummary, 0, len(durations))
-	for name, duration := range durations {
-		if duration >= minDuration {
-			summaryList = append(summaryList, TimeSummary{Name: name, Duration: duration})
-		}
-	}
-
+	summaryList := SortedSummary(durations, minDuration)
 	if len(summaryList) == 0 {
 		fmt.Printf("No activities lasted longer than %s.\n", FormatDuration(minDuration))
 		return
 	}
 
-	sort.Slice(summaryList, func(i, j int) bool {
-		return summaryList[i].Duration > summaryList[j].Duration
-	})
-
 	for _, item := range summaryList {
 		fmt.Printf("%-60s : %s\n", item.Name, FormatDuration(item.Duration))
 	}
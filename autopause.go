@@ -0,0 +1,211 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/thiagokokada/hyprland-go"
+	"github.com/thiagokokada/hyprland-go/helpers"
+)
+
+const (
+	outsideScheduleReason = "outside tracking hours"
+	autoPauseInterval     = 30 * time.Second
+)
+
+// AutoPauseConfig pauses the tracking by itself outside the tracked hours and while
+// one of the rules matches
+type AutoPauseConfig struct {
+	// Hours and Days limit tracking to a daily time span such as "08:00-19:00", on
+	// some weekdays if set, e.g. ["weekdays"]
+	Hours string          `json:"hours"`
+	Days  []string        `json:"days"`
+	Rules []AutoPauseRule `json:"rules"`
+
+	hours clockRange
+	days  map[time.Weekday]bool
+}
+
+// AutoPauseRule pauses the tracking while the focused window matches one of its
+// patterns, which work like category patterns, or while one of its workspaces is active
+type AutoPauseRule struct {
+	Name  string   `json:"name"`
+	Match []string `json:"match"`
+	// Workspaces are globs matched against the name of the active workspace
+	Workspaces []string `json:"workspaces"`
+}
+
+// validate parses the schedule and checks the rules
+func (a *AutoPauseConfig) validate() error {
+	var err error
+	if a.Hours != "" {
+		if a.hours, err = parseClockRange(a.Hours); err != nil {
+			return fmt.Errorf("%v in auto_pause", err)
+		}
+	}
+	if a.days, err = parseDays(a.Days); err != nil {
+		return fmt.Errorf("%v in auto_pause", err)
+	}
+	for _, rule := range a.Rules {
+		if rule.Name == "" {
+			return fmt.Errorf("auto_pause rule without a name")
+		}
+		if len(rule.Match) == 0 && len(rule.Workspaces) == 0 {
+			return fmt.Errorf("auto_pause rule %s has no match patterns or workspaces", rule.Name)
+		}
+		for _, pattern := range append(append([]string{}, rule.Workspaces...), rule.Match...) {
+			if _, err := path.Match(strings.TrimPrefix(pattern, "title:"), ""); err != nil {
+				return fmt.Errorf("invalid pattern %q in auto_pause rule %s: %v", pattern, rule.Name, err)
+			}
+		}
+	}
+	return nil
+}
+
+// Scheduled reports whether t lies within the tracked hours and days
+func (a *AutoPauseConfig) Scheduled(t time.Time) bool {
+	if a.days != nil && !a.days[t.Local().Weekday()] {
+		return false
+	}
+	return a.Hours == "" || a.hours.contains(t)
+}
+
+func (r *AutoPauseRule) matches(app, title, workspace string) bool {
+	for _, pattern := range r.Match {
+		if (app != "" || title != "") && MatchWindowPattern(pattern, app, title) {
+			return true
+		}
+	}
+	for _, pattern := range r.Workspaces {
+		if matched, _ := path.Match(pattern, workspace); matched && workspace != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// Reason returns why tracking should be paused at t, or "" if it should not
+func (a *AutoPauseConfig) Reason(t time.Time, app, title, workspace string) string {
+	if !a.Scheduled(t) {
+		return outsideScheduleReason
+	}
+	for i := range a.Rules {
+		if a.Rules[i].matches(app, title, workspace) {
+			return a.Rules[i].Name
+		}
+	}
+	return ""
+}
+
+// AutoPauser follows the focused window and workspace, including while paused, and
+// pauses or resumes the tracking as the schedule and rules say
+type AutoPauser struct {
+	config *AutoPauseConfig
+	pauses *PauseManager
+
+	mu        sync.Mutex
+	app       string
+	title     string
+	workspace string
+	// changes counts the observations, so that evaluate notices one made meanwhile
+	changes int
+}
+
+func NewAutoPauser(config *AutoPauseConfig, pauses *PauseManager) *AutoPauser {
+	return &AutoPauser{config: config, pauses: pauses}
+}
+
+// ObserveWindow takes note of a newly focused window; a nil AutoPauser ignores it
+func (a *AutoPauser) ObserveWindow(app, title string) {
+	if a == nil {
+		return
+	}
+	a.mu.Lock()
+	a.app, a.title = app, title
+	a.changes++
+	a.mu.Unlock()
+	a.evaluate(time.Now())
+}
+
+// ObserveWorkspace takes note of a newly active workspace; a nil AutoPauser ignores it
+func (a *AutoPauser) ObserveWorkspace(name string) {
+	if a == nil {
+		return
+	}
+	a.mu.Lock()
+	a.workspace = name
+	a.changes++
+	a.mu.Unlock()
+	a.evaluate(time.Now())
+}
+
+// evaluate pauses or resumes for the current window and workspace. The pause is
+// changed without holding the lock, and again if the window or workspace changed
+// meanwhile, so that an earlier evaluation does not have the last word.
+func (a *AutoPauser) evaluate(now time.Time) {
+	for {
+		a.mu.Lock()
+		app, title, workspace, changes := a.app, a.title, a.workspace, a.changes
+		a.mu.Unlock()
+
+		a.pauses.SetAuto(a.config.Reason(now, app, title, workspace), now)
+
+		a.mu.Lock()
+		done := a.changes == changes
+		a.mu.Unlock()
+		if done {
+			return
+		}
+	}
+}
+
+// seed looks up the focused window and workspace, which are only reported by Hyprland
+// when they change
+func (a *AutoPauser) seed() {
+	socket, err := helpers.GetSocket(helpers.RequestSocket)
+	if err != nil {
+		log.Printf("Error looking up the active window: %v", err)
+		return
+	}
+	client := hyprland.NewClient(socket)
+	if window, err := client.ActiveWindow(); err != nil {
+		log.Printf("Error looking up the active window: %v", err)
+	} else {
+		a.mu.Lock()
+		a.app, a.title = window.Class, window.Title
+		a.mu.Unlock()
+	}
+	if workspace, err := client.ActiveWorkspace(); err != nil {
+		log.Printf("Error looking up the active workspace: %v", err)
+	} else {
+		a.mu.Lock()
+		a.workspace = workspace.Name
+		a.mu.Unlock()
+	}
+}
+
+// Run evaluates the schedule as time passes, until the context is canceled
+func (a *AutoPauser) Run(ctx context.Context, wg *sync.WaitGroup) {
+	defer wg.Done()
+
+	if len(a.config.Rules) > 0 {
+		a.seed()
+	}
+	a.evaluate(time.Now())
+
+	ticker := time.NewTicker(autoPauseInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			a.evaluate(now)
+		}
+	}
+}
@@ -0,0 +1,184 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+	"time"
+)
+
+const (
+	// DefaultBarTarget is the active time per day shown as 100% by -bar
+	DefaultBarTarget = 8 * time.Hour
+
+	barTopApps        = 5
+	barReconnectDelay = 10 * time.Second
+)
+
+// BarOutput is a line of the Waybar custom module protocol, also readable by Eww
+type BarOutput struct {
+	Text       string `json:"text"`
+	Tooltip    string `json:"tooltip"`
+	Class      string `json:"class"`
+	Percentage int    `json:"percentage"`
+}
+
+// formatBarDuration formats d compactly for a status bar, e.g. "2h 05m" or "12m"
+func formatBarDuration(d time.Duration) string {
+	d = d.Truncate(time.Minute)
+	if d >= time.Hour {
+		return fmt.Sprintf("%dh %02dm", d/time.Hour, (d%time.Hour)/time.Minute)
+	}
+	return fmt.Sprintf("%dm", d/time.Minute)
+}
+
+// renderBar builds the module output from a status and summary fetched age ago
+func renderBar(status StatusResult, summary SummaryResult, age time.Duration, target time.Duration) BarOutput {
+	// While active, the focused window and today's total keep counting
+	var counting time.Duration
+	if !status.Paused && !status.Idle && status.App != "" {
+		counting = age
+	}
+	today := time.Duration(status.ActiveSeconds)*time.Second + counting
+
+	out := BarOutput{Class: "active"}
+	switch {
+	case status.Paused:
+		out.Class = "paused"
+		out.Text = "paused"
+	case status.Idle:
+		out.Class = "idle"
+		out.Text = "idle"
+	case status.App != "":
+		elapsed := time.Duration(status.ElapsedSeconds)*time.Second + counting
+		out.Text = fmt.Sprintf("%s %s", status.App, formatBarDuration(elapsed))
+	default:
+		out.Text = "active"
+	}
+	out.Text += " · " + formatBarDuration(today)
+	if target > 0 {
+		out.Percentage = min(int(today*100/target), 100)
+	}
+
+	lines := []string{fmt.Sprintf("Today: %s (%s)", formatBarDuration(today), status.State())}
+	if status.Tag != "" {
+		lines = append(lines, "Tag: "+status.Tag)
+	}
+	for _, app := range summary.Apps {
+		lines = append(lines, fmt.Sprintf("%s  %s", app.App, formatBarDuration(time.Duration(app.Seconds)*time.Second)))
+	}
+	out.Tooltip = strings.Join(lines, "\n")
+	return out
+}
+
+// barPrinter writes the module output, skipping lines identical to the previous one
+type barPrinter struct {
+	w    io.Writer
+	last BarOutput
+}
+
+func (p *barPrinter) print(out BarOutput) {
+	if out == p.last {
+		return
+	}
+	p.last = out
+	data, err := json.Marshal(out)
+	if err != nil {
+		log.Printf("Error encoding bar output: %v", err)
+		return
+	}
+	fmt.Fprintln(p.w, string(data))
+}
+
+// RunBar runs as a Waybar or Eww module: it prints a JSON line whenever the state of
+// the daemon changes, and once a minute while the elapsed time counts up. It waits for
+// the daemon to come back when the connection is lost.
+func RunBar(target time.Duration) {
+	printer := &barPrinter{w: os.Stdout}
+	for {
+		err := runBarSession(printer, target)
+		log.Printf("Bar: %v", err)
+		printer.print(BarOutput{Text: "not tracking", Tooltip: "The hyprtracker daemon is not running", Class: "stopped"})
+		time.Sleep(barReconnectDelay)
+	}
+}
+
+// runBarSession follows the daemon until the connection fails
+func runBarSession(printer *barPrinter, target time.Duration) error {
+	events, err := DialControl(SocketPath)
+	if err != nil {
+		return err
+	}
+	defer events.Close()
+	if err := events.Subscribe(nil); err != nil {
+		return err
+	}
+
+	changed := make(chan struct{}, 1)
+	failed := make(chan error, 1)
+	go func() {
+		for {
+			if _, err := events.NextEvent(); err != nil {
+				failed <- err
+				return
+			}
+			select {
+			case changed <- struct{}{}:
+			default:
+			}
+		}
+	}()
+
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+	var status StatusResult
+	var summary SummaryResult
+	var fetched time.Time
+	var tick <-chan time.Time
+
+	// The state is fetched on a connection of its own, so that requests never wait
+	// behind streamed events
+	refresh := func() error {
+		client, err := DialControl(SocketPath)
+		if err != nil {
+			return err
+		}
+		defer client.Close()
+		status, summary = StatusResult{}, SummaryResult{}
+		if err := client.Call("status", nil, &status); err != nil {
+			return err
+		}
+		if err := client.Call("summary", SummaryArgs{Range: "today", Limit: barTopApps}, &summary); err != nil {
+			return err
+		}
+		fetched = time.Now()
+		printer.print(renderBar(status, summary, 0, target))
+
+		// Only count up while active, so an idle or paused daemon costs nothing
+		tick = nil
+		if status.State() == "active" && status.App != "" {
+			ticker.Reset(time.Minute)
+			tick = ticker.C
+		}
+		return nil
+	}
+
+	if err := refresh(); err != nil {
+		return err
+	}
+	for {
+		select {
+		case err := <-failed:
+			return err
+		case <-changed:
+			if err := refresh(); err != nil {
+				return err
+			}
+		case now := <-tick:
+			printer.print(renderBar(status, summary, now.Sub(fetched), target))
+		}
+	}
+}
@@ -0,0 +1,267 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Break events are recorded when a reminder is sent, and when it is followed by a
+// break, snoozed from the notification or ignored until the next reminder
+const (
+	BreakReminderEventType = "break_reminder"
+	BreakTakenEventType    = "break_taken"
+	BreakSnoozedEventType  = "break_snoozed"
+	BreakIgnoredEventType  = "break_ignored"
+
+	DefaultBreakResetAfter = 5 * time.Minute
+	DefaultBreakSnooze     = 10 * time.Minute
+	breakMonitorInterval   = 30 * time.Second
+	breakSnoozeAction      = "snooze"
+)
+
+// BreakConfig enables break reminders after a stretch of continuous activity
+type BreakConfig struct {
+	// After is the continuous activity after which a break is due, e.g. "50m"
+	After string `json:"after"`
+	// ResetAfter is the shortest idle, lock or suspend period that counts as a break
+	ResetAfter string `json:"reset_after"`
+	// Snooze is how long a snoozed or ignored reminder waits before it is repeated
+	Snooze string `json:"snooze"`
+
+	after      time.Duration
+	resetAfter time.Duration
+	snooze     time.Duration
+}
+
+// validate parses the durations and fills in the defaults
+func (b *BreakConfig) validate() error {
+	var err error
+	if b.After == "" {
+		return fmt.Errorf("breaks need an \"after\" duration, e.g. \"50m\"")
+	}
+	if b.after, err = parseConfigDuration("breaks after", b.After, 0); err != nil {
+		return err
+	}
+	if b.resetAfter, err = parseConfigDuration("breaks reset_after", b.ResetAfter, DefaultBreakResetAfter); err != nil {
+		return err
+	}
+	if b.snooze, err = parseConfigDuration("breaks snooze", b.Snooze, DefaultBreakSnooze); err != nil {
+		return err
+	}
+	return nil
+}
+
+func isBreakEvent(eventType string) bool {
+	return strings.HasPrefix(eventType, "break_")
+}
+
+// breakTimer follows the activity stream and decides when a break is due
+type breakTimer struct {
+	config *BreakConfig
+	// activeSince is the end of the last break, or the daemon start
+	activeSince time.Time
+	// awaySince is the start of the current idle, lock or suspend period
+	awaySince time.Time
+	away      map[string]bool
+	// nextReminder is when the next reminder is sent while the user is active
+	nextReminder time.Time
+	// pending is set while a reminder has neither been followed by a break nor snoozed
+	pending bool
+	// taken is set once the current away period is long enough to count as a break
+	taken bool
+}
+
+func newBreakTimer(config *BreakConfig, now time.Time) *breakTimer {
+	t := &breakTimer{config: config, away: make(map[string]bool)}
+	t.reset(now)
+	return t
+}
+
+func (t *breakTimer) reset(at time.Time) {
+	t.activeSince = at
+	t.nextReminder = at.Add(t.config.after)
+	t.pending = false
+	t.taken = false
+}
+
+// observe updates the timer with an idle, lock or suspend marker
+func (t *breakTimer) observe(entry LogEntry) {
+	kind, start, ok := awayEventKind(entry.EventType)
+	if !ok {
+		return
+	}
+	if start {
+		if len(t.away) == 0 {
+			t.awaySince = entry.Timestamp
+		}
+		t.away[kind] = true
+		return
+	}
+	if !t.away[kind] {
+		return
+	}
+	delete(t.away, kind)
+	if len(t.away) == 0 && (t.taken || entry.Timestamp.Sub(t.awaySince) >= t.config.resetAfter) {
+		t.reset(entry.Timestamp)
+	}
+}
+
+// RunBreakMonitor sends a break reminder after the configured stretch of continuous
+// activity, repeating it while no break is taken. Idle, lock and suspend periods of at
+// least reset_after restart the count. Reminders and their outcome are written to logChan.
+func RunBreakMonitor(ctx context.Context, wg *sync.WaitGroup, hub *ActivityHub, logChan chan<- LogEntry, config *BreakConfig, notifier *Notifier) {
+	defer wg.Done()
+
+	entries := hub.Subscribe(100)
+	defer hub.Unsubscribe(entries)
+
+	snoozed := make(chan time.Time, 1)
+	timer := newBreakTimer(config, time.Now())
+
+	record := func(eventType string, at time.Time) {
+		entry := LogEntry{Timestamp: at, EventType: eventType}
+		entry.EventData.Title = FormatDuration(at.Sub(timer.activeSince).Round(time.Minute))
+		select {
+		case logChan <- entry:
+		case <-ctx.Done():
+		}
+	}
+
+	check := func(now time.Time) {
+		if len(timer.away) > 0 {
+			if !timer.taken && now.Sub(timer.awaySince) >= config.resetAfter {
+				timer.taken = true
+				if timer.pending {
+					record(BreakTakenEventType, now)
+					timer.pending = false
+				}
+			}
+			return
+		}
+		if now.Before(timer.nextReminder) {
+			return
+		}
+
+		if timer.pending {
+			record(BreakIgnoredEventType, now)
+		}
+		active := FormatDuration(now.Sub(timer.activeSince).Round(time.Minute))
+		log.Printf("Break reminder after %s of activity", active)
+		record(BreakReminderEventType, now)
+		timer.pending = true
+		timer.nextReminder = now.Add(config.snooze)
+
+		notifier.NotifyWithActions("break", "Time for a break",
+			fmt.Sprintf("You have been active for %s.", active),
+			[]string{breakSnoozeAction, "Snooze " + FormatDuration(config.snooze)},
+			func(action string) {
+				if action == breakSnoozeAction {
+					select {
+					case snoozed <- time.Now():
+					default:
+					}
+				}
+			})
+	}
+
+	ticker := time.NewTicker(breakMonitorInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case entry := <-entries:
+			timer.observe(entry)
+		case at := <-snoozed:
+			if timer.pending {
+				log.Printf("Break reminder snoozed for %s", FormatDuration(config.snooze))
+				record(BreakSnoozedEventType, at)
+				timer.pending = false
+				timer.nextReminder = at.Add(config.snooze)
+			}
+		case now := <-ticker.C:
+			check(now)
+		}
+	}
+}
+
+// BreakDay counts the reminders of one day or week and their outcome
+type BreakDay struct {
+	Period    time.Time
+	Reminders int
+	Taken     int
+	Snoozed   int
+	Ignored   int
+}
+
+// TakenPercent is the share of reminders followed by a break
+func (b BreakDay) TakenPercent() float64 {
+	if b.Reminders == 0 {
+		return 0
+	}
+	return float64(b.Taken) / float64(b.Reminders) * 100
+}
+
+// CountBreaks sums the break events per bucket, oldest first
+func CountBreaks(entries []LogEntry, unit BucketUnit) []BreakDay {
+	var days []BreakDay
+	index := make(map[time.Time]int)
+	for _, entry := range entries {
+		if !isBreakEvent(entry.EventType) {
+			continue
+		}
+		period := BucketStart(entry.Timestamp, unit)
+		i, ok := index[period]
+		if !ok {
+			i = len(days)
+			index[period] = i
+			days = append(days, BreakDay{Period: period})
+		}
+		switch entry.EventType {
+		case BreakReminderEventType:
+			days[i].Reminders++
+		case BreakTakenEventType:
+			days[i].Taken++
+		case BreakSnoozedEventType:
+			days[i].Snoozed++
+		case BreakIgnoredEventType:
+			days[i].Ignored++
+		}
+	}
+	return days
+}
+
+func generateBreaksReport(db *Database, startTime, endTime time.Time, config AnalysisConfig) {
+	entries, err := db.GetEvents(startTime, endTime)
+	if err != nil {
+		log.Fatalf("Error retrieving events from database: %v", err)
+	}
+
+	columns := []string{"Reminders", "Taken", "Snoozed", "Ignored", "Taken %"}
+	daysTable := &ReportTable{Title: "Break Reminders Per Day", Columns: append([]string{"Date"}, columns...)}
+	var total BreakDay
+	for _, day := range CountBreaks(entries, BucketDay) {
+		daysTable.AddRow(BucketLabel(day.Period, BucketDay), day.Reminders, day.Taken, day.Snoozed, day.Ignored, day.TakenPercent())
+		total.Reminders += day.Reminders
+		total.Taken += day.Taken
+		total.Snoozed += day.Snoozed
+		total.Ignored += day.Ignored
+	}
+	if total.Reminders > 0 {
+		daysTable.AddRow("Total", total.Reminders, total.Taken, total.Snoozed, total.Ignored, total.TakenPercent())
+	}
+
+	weeksTable := &ReportTable{Title: "Break Reminders Per Week", Columns: append([]string{"Week"}, columns...)}
+	for _, week := range CountBreaks(entries, BucketWeek) {
+		weeksTable.AddRow(BucketLabel(week.Period, BucketWeek), week.Reminders, week.Taken, week.Snoozed, week.Ignored, week.TakenPercent())
+	}
+
+	if err := RenderTables(os.Stdout, config.Format, daysTable, weeksTable); err != nil {
+		log.Fatalf("Error writing report: %v", err)
+	}
+}
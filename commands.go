@@ -0,0 +1,293 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Command is a subcommand run as "hyprtracker <name> [flags]"
+type Command struct {
+	Name  string
+	Usage string
+	Run   func(args []string) error
+}
+
+var commands = []Command{
+	{"add", "add -from 10:00 -to 11:00 -label standup [-category meetings] [-exclude]", runAddCommand},
+	{"edit", "edit <id> [-from TIME] [-to TIME] [-label LABEL] [-category CATEGORY] [-exclude=true|false]", runEditCommand},
+	{"split", "split <id> -at TIME", runSplitCommand},
+	{"delete", "delete <id>", runDeleteCommand},
+	{"entries", "entries [-time-range week] [-format text]", runEntriesCommand},
+	{"search", "search [-time-range month] [-limit 50] [-format text] QUERY", runSearchCommand},
+	{"status", "status [-json]", runStatusCommand},
+	{"pause", "pause [-for 30m | -until 13:00] [-reason lunch]", runPauseCommand},
+	{"resume", "resume", runResumeCommand},
+	{"subscribe", "subscribe [-events focus,idle,pause,tag,goal]", runSubscribeCommand},
+}
+
+// FindCommand returns the subcommand with the given name
+func FindCommand(name string) (Command, bool) {
+	for _, c := range commands {
+		if c.Name == name {
+			return c, true
+		}
+	}
+	return Command{}, false
+}
+
+// PrintCommandUsage lists the subcommands after the flag defaults in -help
+func PrintCommandUsage() {
+	fmt.Fprintln(flag.CommandLine.Output(), "\nCommands (run \"hyprtracker <command> -help\" for their flags):")
+	for _, c := range commands {
+		fmt.Fprintf(flag.CommandLine.Output(), "  hyprtracker %s\n", c.Usage)
+	}
+}
+
+// newCommandFlags returns a flag set for a subcommand with the common -db-path flag
+func newCommandFlags(name string) (*flag.FlagSet, *string) {
+	fs := flag.NewFlagSet("hyprtracker "+name, flag.ExitOnError)
+	dbPath := fs.String("db-path", DefaultDBPath, "Path to the SQLite database file")
+	return fs, dbPath
+}
+
+// parseWithID parses the flags of a subcommand taking an entry id, which may be
+// given before or after the flags
+func parseWithID(fs *flag.FlagSet, args []string) (int64, error) {
+	var idArg string
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		idArg, args = args[0], args[1:]
+	}
+	if err := fs.Parse(args); err != nil {
+		return 0, err
+	}
+	if idArg == "" {
+		idArg = fs.Arg(0)
+	}
+	if idArg == "" {
+		return 0, fmt.Errorf("missing entry id")
+	}
+	id, err := strconv.ParseInt(strings.TrimPrefix(idArg, "#"), 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid entry id %q", idArg)
+	}
+	return id, nil
+}
+
+func describeManualEntry(m ManualEntry) string {
+	description := fmt.Sprintf("#%d %s – %s (%s)", m.ID,
+		m.Start.Local().Format("Mon 2006-01-02 15:04"), m.End.Local().Format("15:04"), FormatDuration(m.Duration()))
+	if m.Label != "" {
+		description += " " + m.Label
+	}
+	if m.Category != "" {
+		description += " [" + m.Category + "]"
+	}
+	if m.Excluded {
+		description += " (excluded)"
+	}
+	return description
+}
+
+// flagSet reports whether the flag was given on the command line
+func flagSet(fs *flag.FlagSet, name string) bool {
+	set := false
+	fs.Visit(func(f *flag.Flag) {
+		if f.Name == name {
+			set = true
+		}
+	})
+	return set
+}
+
+func runAddCommand(args []string) error {
+	fs, dbPath := newCommandFlags("add")
+	from := fs.String("from", "", "Start of the entry (see -at for the accepted formats)")
+	to := fs.String("to", "", "End of the entry (see -at for the accepted formats)")
+	label := fs.String("label", "", "What the time was spent on")
+	category := fs.String("category", "", "Category of the entry (default: categorized by the configured rules)")
+	exclude := fs.Bool("exclude", false, "Remove the tracked time in the range from reports instead of adding an entry")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *from == "" || *to == "" {
+		return fmt.Errorf("both -from and -to are required")
+	}
+
+	now := time.Now()
+	entry := ManualEntry{Label: *label, Category: *category, Source: ManualSourceCLI, Excluded: *exclude}
+	var err error
+	if entry.Start, err = ParseTimeExpression(*from, now); err != nil {
+		return err
+	}
+	if entry.End, err = ParseTimeExpression(*to, now); err != nil {
+		return err
+	}
+
+	db, err := OpenDatabase(*dbPath)
+	if err != nil {
+		return fmt.Errorf("error opening database: %v", err)
+	}
+	defer db.Close()
+
+	if entry, err = db.AddManualEntry(entry); err != nil {
+		return err
+	}
+	fmt.Println("Added", describeManualEntry(entry))
+	return nil
+}
+
+func runEditCommand(args []string) error {
+	fs, dbPath := newCommandFlags("edit")
+	from := fs.String("from", "", "New start of the entry")
+	to := fs.String("to", "", "New end of the entry")
+	label := fs.String("label", "", "New label")
+	category := fs.String("category", "", "New category ('-' to categorize by the configured rules)")
+	exclude := fs.Bool("exclude", false, "Whether the entry only removes tracked time (-exclude=false to count it again)")
+	id, err := parseWithID(fs, args)
+	if err != nil {
+		return err
+	}
+
+	db, err := OpenDatabase(*dbPath)
+	if err != nil {
+		return fmt.Errorf("error opening database: %v", err)
+	}
+	defer db.Close()
+
+	entry, err := db.GetManualEntry(id)
+	if err != nil {
+		return err
+	}
+	// Relative times such as "10:00" refer to the day of the entry
+	day := entry.Start.Local()
+	if *from != "" {
+		if entry.Start, err = ParseTimeExpression(*from, day); err != nil {
+			return err
+		}
+	}
+	if *to != "" {
+		if entry.End, err = ParseTimeExpression(*to, day); err != nil {
+			return err
+		}
+	}
+	if *label != "" {
+		entry.Label = *label
+	}
+	switch *category {
+	case "":
+	case "-":
+		entry.Category = ""
+	default:
+		entry.Category = *category
+	}
+	if flagSet(fs, "exclude") {
+		entry.Excluded = *exclude
+	}
+
+	if entry, err = db.UpdateManualEntry(entry); err != nil {
+		return err
+	}
+	fmt.Println("Updated", describeManualEntry(entry))
+	return nil
+}
+
+func runSplitCommand(args []string) error {
+	fs, dbPath := newCommandFlags("split")
+	at := fs.String("at", "", "Time at which the entry is split")
+	id, err := parseWithID(fs, args)
+	if err != nil {
+		return err
+	}
+	if *at == "" {
+		return fmt.Errorf("-at is required")
+	}
+
+	db, err := OpenDatabase(*dbPath)
+	if err != nil {
+		return fmt.Errorf("error opening database: %v", err)
+	}
+	defer db.Close()
+
+	entry, err := db.GetManualEntry(id)
+	if err != nil {
+		return err
+	}
+	splitAt, err := ParseTimeExpression(*at, entry.Start.Local())
+	if err != nil {
+		return err
+	}
+	first, second, err := db.SplitManualEntry(id, splitAt)
+	if err != nil {
+		return err
+	}
+	fmt.Println("Split into", describeManualEntry(first), "and", describeManualEntry(second))
+	return nil
+}
+
+func runDeleteCommand(args []string) error {
+	fs, dbPath := newCommandFlags("delete")
+	id, err := parseWithID(fs, args)
+	if err != nil {
+		return err
+	}
+
+	db, err := OpenDatabase(*dbPath)
+	if err != nil {
+		return fmt.Errorf("error opening database: %v", err)
+	}
+	defer db.Close()
+
+	entry, err := db.GetManualEntry(id)
+	if err != nil {
+		return err
+	}
+	if err := db.DeleteManualEntry(id); err != nil {
+		return err
+	}
+	fmt.Println("Deleted", describeManualEntry(entry))
+	return nil
+}
+
+func runEntriesCommand(args []string) error {
+	fs, dbPath := newCommandFlags("entries")
+	timeRange := fs.String("time-range", "month", "Time range: 'day', 'week', 'month', 'year', or 'all'")
+	format := fs.String("format", FormatText, "Output format: 'text', 'csv', 'markdown' or 'json'")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if err := ValidateOutputFormat(*format); err != nil {
+		return err
+	}
+
+	db, err := OpenDatabase(*dbPath)
+	if err != nil {
+		return fmt.Errorf("error opening database: %v", err)
+	}
+	defer db.Close()
+
+	endTime := time.Now()
+	startTime, description := ResolveTimeRange(*timeRange, endTime)
+	// Entries may be planned ahead, e.g. a meeting later today
+	entries, err := db.GetManualEntries(startTime, endTime.AddDate(1, 0, 0))
+	if err != nil {
+		return err
+	}
+
+	table := &ReportTable{
+		Title:   "Manual Entries in " + description,
+		Columns: []string{"ID", "Start", "End", "Duration", "Label", "Category", "Excluded", "Source", "Created", "Updated", "Split From"},
+	}
+	for _, m := range entries {
+		splitFrom := ""
+		if m.SplitFrom != 0 {
+			splitFrom = fmt.Sprintf("#%d", m.SplitFrom)
+		}
+		table.AddRow(m.ID, m.Start.Local(), m.End.Local(), m.Duration(), m.Label, m.Category, m.Excluded, m.Source,
+			m.CreatedAt.Local(), m.UpdatedAt.Local(), splitFrom)
+	}
+	return RenderTables(os.Stdout, *format, table)
+}
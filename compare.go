@@ -0,0 +1,162 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// SignedDuration is a duration delta that keeps its sign when rendered
+type SignedDuration time.Duration
+
+func (d SignedDuration) String() string {
+	if d < 0 {
+		return "-" + FormatDuration(-time.Duration(d))
+	}
+	return "+" + FormatDuration(time.Duration(d))
+}
+
+// ComparisonRow holds the time spent on one app/category in both ranges
+type ComparisonRow struct {
+	Name     string
+	Current  time.Duration
+	Previous time.Duration
+}
+
+func (r ComparisonRow) Delta() time.Duration {
+	return r.Current - r.Previous
+}
+
+// Status flags rows that only appear in one of the two ranges
+func (r ComparisonRow) Status() string {
+	switch {
+	case r.Previous == 0 && r.Current > 0:
+		return "new"
+	case r.Current == 0 && r.Previous > 0:
+		return "gone"
+	default:
+		return ""
+	}
+}
+
+// ParseDateRange parses an inclusive "YYYY-MM-DD..YYYY-MM-DD" range of local days
+func ParseDateRange(s string) (time.Time, time.Time, error) {
+	from, to, ok := strings.Cut(s, "..")
+	if !ok {
+		return time.Time{}, time.Time{}, fmt.Errorf("invalid range %q (expected YYYY-MM-DD..YYYY-MM-DD)", s)
+	}
+	start, err := ParseDay(strings.TrimSpace(from))
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+	lastDay, err := ParseDay(strings.TrimSpace(to))
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+	if lastDay.Before(start) {
+		return time.Time{}, time.Time{}, fmt.Errorf("invalid range %q: end is before start", s)
+	}
+	return start, NextBucket(lastDay, BucketDay), nil
+}
+
+// ResolveComparisonRange returns the baseline range for -compare: either the range
+// of equal length immediately before the current one, or an explicit date range
+func ResolveComparisonRange(compare, timeRange string, startTime, endTime time.Time) (time.Time, time.Time, error) {
+	if compare != "previous" {
+		return ParseDateRange(compare)
+	}
+	if startTime.IsZero() {
+		return time.Time{}, time.Time{}, fmt.Errorf("cannot compare 'all' with a previous range")
+	}
+	if strings.Contains(timeRange, "..") {
+		days := int(endTime.Sub(startTime).Hours()+12) / 24
+		return startTime.AddDate(0, 0, -days), startTime, nil
+	}
+	previousStart, _ := ResolveTimeRange(timeRange, startTime)
+	return previousStart, startTime, nil
+}
+
+// CompareDurations merges two duration maps into rows sorted by the largest change first.
+// Rows below minDuration in both ranges are dropped.
+func CompareDurations(current, previous map[string]time.Duration, minDuration time.Duration) []ComparisonRow {
+	names := make(map[string]bool)
+	for name := range current {
+		names[name] = true
+	}
+	for name := range previous {
+		names[name] = true
+	}
+
+	var rows []ComparisonRow
+	for name := range names {
+		row := ComparisonRow{Name: name, Current: current[name], Previous: previous[name]}
+		if row.Current < minDuration && row.Previous < minDuration {
+			continue
+		}
+		rows = append(rows, row)
+	}
+
+	sort.Slice(rows, func(i, j int) bool {
+		di, dj := rows[i].Delta().Abs(), rows[j].Delta().Abs()
+		if di != dj {
+			return di > dj
+		}
+		return rows[i].Name < rows[j].Name
+	})
+
+	return rows
+}
+
+func groupDurations(intervals []Interval, keyFn func(Interval) string) map[string]time.Duration {
+	durations := make(map[string]time.Duration)
+	for _, iv := range intervals {
+		durations[keyFn(iv)] += iv.Duration()
+	}
+	return durations
+}
+
+func generateComparisonReport(db *Database, startTime, endTime time.Time, config AnalysisConfig) {
+	previousStart, previousEnd, err := ResolveComparisonRange(config.Compare, config.TimeRange, startTime, endTime)
+	if err != nil {
+		log.Fatalf("Error: %v", err)
+	}
+
+	keyFn := config.Config.GroupKeyFunc(config.GroupBy)
+	current := groupDurations(loadIntervals(db, startTime, endTime, config), keyFn)
+	previous := groupDurations(loadIntervals(db, previousStart, previousEnd, config), keyFn)
+	rows := CompareDurations(current, previous, config.TotalMinDuration())
+
+	const dateFormat = "2006-01-02 15:04"
+	table := &ReportTable{
+		Title: fmt.Sprintf("Comparison: %s – %s vs. %s – %s",
+			startTime.Format(dateFormat), endTime.Format(dateFormat),
+			previousStart.Format(dateFormat), previousEnd.Format(dateFormat)),
+		Columns: []string{groupByHeaders[config.GroupBy], "Current", "Previous", "Change", "Change %", "Status"},
+	}
+
+	var totalCurrent, totalPrevious time.Duration
+	for _, row := range rows {
+		table.AddRow(row.Name, row.Current, row.Previous, SignedDuration(row.Delta()), changePercent(row.Current, row.Previous), row.Status())
+		totalCurrent += row.Current
+		totalPrevious += row.Previous
+	}
+	if len(rows) > 0 {
+		table.AddRow("Total", totalCurrent, totalPrevious, SignedDuration(totalCurrent-totalPrevious), changePercent(totalCurrent, totalPrevious), "")
+	}
+
+	if err := RenderTables(os.Stdout, config.Format, table); err != nil {
+		log.Fatalf("Error writing report: %v", err)
+	}
+}
+
+// changePercent returns the relative change as a percentage, or nil without a baseline
+func changePercent(current, previous time.Duration) any {
+	if previous == 0 {
+		return nil
+	}
+	return math.Round(float64(current-previous)/float64(previous)*1000) / 10
+}
@@ -0,0 +1,212 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const UncategorizedCategory = "other"
+
+// Config holds the optional user configuration loaded from a JSON file
+type Config struct {
+	Categories    []CategoryRule     `json:"categories"`
+	Projects      []ProjectRule      `json:"projects"`
+	Timesheet     TimesheetConfig    `json:"timesheet"`
+	Goals         []Goal             `json:"goals"`
+	Breaks        *BreakConfig       `json:"breaks"`
+	FocusSessions FocusSessionConfig `json:"focus_sessions"`
+	Enforcement   *EnforcementConfig `json:"enforcement"`
+	AutoPause     *AutoPauseConfig   `json:"auto_pause"`
+}
+
+// CategoryRule maps windows to a category. Each pattern is a glob matched against
+// the window class, or against the window title when prefixed with "title:".
+// Rules are evaluated in order and the first match wins.
+type CategoryRule struct {
+	Name  string   `json:"name"`
+	Match []string `json:"match"`
+}
+
+func GetDefaultConfigPath() string {
+	configDir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(configDir, "hyprtracker", "config.json")
+}
+
+// LoadConfig reads the config file at configPath. A missing file is not an error
+// and results in an empty configuration.
+func LoadConfig(configPath string) (*Config, error) {
+	config := &Config{}
+	if configPath == "" {
+		return config, nil
+	}
+
+	data, err := os.ReadFile(configPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return config, nil
+		}
+		return nil, fmt.Errorf("failed to read config file: %v", err)
+	}
+
+	if err := json.Unmarshal(data, config); err != nil {
+		return nil, fmt.Errorf("failed to parse config file %s: %v", configPath, err)
+	}
+
+	for _, rule := range config.Categories {
+		if rule.Name == "" {
+			return nil, fmt.Errorf("invalid config file %s: category without a name", configPath)
+		}
+		for _, pattern := range rule.Match {
+			if _, err := path.Match(strings.TrimPrefix(pattern, "title:"), ""); err != nil {
+				return nil, fmt.Errorf("invalid pattern %q in category %s: %v", pattern, rule.Name, err)
+			}
+		}
+	}
+
+	for _, project := range config.Projects {
+		if project.Name == "" {
+			return nil, fmt.Errorf("invalid config file %s: project without a name", configPath)
+		}
+		for _, pattern := range project.Match {
+			pattern = strings.TrimPrefix(strings.TrimPrefix(strings.TrimPrefix(pattern, "title:"), "category:"), "tag:")
+			if _, err := path.Match(pattern, ""); err != nil {
+				return nil, fmt.Errorf("invalid pattern %q in project %s: %v", pattern, project.Name, err)
+			}
+		}
+	}
+	if err := config.Timesheet.validate(); err != nil {
+		return nil, fmt.Errorf("invalid config file %s: %v", configPath, err)
+	}
+	goalNames := make(map[string]bool)
+	for i := range config.Goals {
+		if err := config.Goals[i].validate(); err != nil {
+			return nil, fmt.Errorf("invalid config file %s: %v", configPath, err)
+		}
+		if goalNames[config.Goals[i].Name] {
+			return nil, fmt.Errorf("invalid config file %s: duplicate goal %s", configPath, config.Goals[i].Name)
+		}
+		goalNames[config.Goals[i].Name] = true
+	}
+	if err := config.FocusSessions.validate(); err != nil {
+		return nil, fmt.Errorf("invalid config file %s: %v", configPath, err)
+	}
+	if category := config.FocusSessions.Category; category != "" && !config.HasCategory(category) {
+		return nil, fmt.Errorf("invalid config file %s: unknown focus_sessions category %q", configPath, category)
+	}
+	if config.Enforcement != nil {
+		if err := config.Enforcement.validate(); err != nil {
+			return nil, fmt.Errorf("invalid config file %s: %v", configPath, err)
+		}
+	}
+	if config.Breaks != nil {
+		if err := config.Breaks.validate(); err != nil {
+			return nil, fmt.Errorf("invalid config file %s: %v", configPath, err)
+		}
+	}
+	if config.AutoPause != nil {
+		if err := config.AutoPause.validate(); err != nil {
+			return nil, fmt.Errorf("invalid config file %s: %v", configPath, err)
+		}
+	}
+
+	return config, nil
+}
+
+// parseConfigDuration parses a positive duration setting, returning fallback if it is empty
+func parseConfigDuration(name, value string, fallback time.Duration) (time.Duration, error) {
+	if value == "" {
+		return fallback, nil
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil || d <= 0 {
+		return 0, fmt.Errorf("invalid %s %q (expected a duration such as \"10m\")", name, value)
+	}
+	return d, nil
+}
+
+// MatchWindowPattern matches a single class or "title:" glob against a window, ignoring case
+func MatchWindowPattern(pattern, app, title string) bool {
+	target := app
+	if strings.HasPrefix(pattern, "title:") {
+		pattern = strings.TrimPrefix(pattern, "title:")
+		target = title
+	}
+	matched, _ := path.Match(strings.ToLower(pattern), strings.ToLower(target))
+	return matched
+}
+
+// Categorize returns the category of the first rule matching the window
+func (c *Config) Categorize(app, title string) string {
+	if c != nil {
+		for _, rule := range c.Categories {
+			for _, pattern := range rule.Match {
+				if MatchWindowPattern(pattern, app, title) {
+					return rule.Name
+				}
+			}
+		}
+	}
+	return UncategorizedCategory
+}
+
+// HasCategory reports whether name is a configured category or the fallback category
+func (c *Config) HasCategory(name string) bool {
+	if name == UncategorizedCategory {
+		return true
+	}
+	if c != nil {
+		for _, rule := range c.Categories {
+			if rule.Name == name {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// CategorizeInterval returns the category given to the interval, or the one of the
+// first rule matching its window
+func (c *Config) CategorizeInterval(iv Interval) string {
+	if iv.Category != "" {
+		return iv.Category
+	}
+	return c.Categorize(iv.App, iv.Title)
+}
+
+// GroupKeyFunc returns the function used to group intervals for the given -group-by value
+func (c *Config) GroupKeyFunc(groupBy string) func(Interval) string {
+	switch groupBy {
+	case "category":
+		return c.CategorizeInterval
+	case "window":
+		return Interval.WindowKey
+	case "tag":
+		return Interval.TagName
+	default:
+		return func(iv Interval) string {
+			return iv.App
+		}
+	}
+}
+
+var groupByHeaders = map[string]string{
+	"app":      "Application",
+	"category": "Category",
+	"window":   "Window",
+	"tag":      "Tag",
+}
+
+func ValidateGroupBy(groupBy string) error {
+	if _, ok := groupByHeaders[groupBy]; !ok {
+		return fmt.Errorf("invalid group-by %q (must be 'app', 'category', 'window' or 'tag')", groupBy)
+	}
+	return nil
+}
@@ -7,27 +7,51 @@ import (
 	"os/signal"
 	"sync"
 	"syscall"
+	"time"
 
 	"fyne.io/systray"
+	"github.com/godbus/dbus/v5"
 	"github.com/thiagokokada/hyprland-go/event"
 )
 
 func RunDaemonWithConfig(config LoggerConfig) {
+	// A socket-activated daemon is passed its control socket by systemd
+	activated, err := ActivatedListener()
+	if err != nil {
+		log.Fatalf("Error: %v", err)
+	}
+
+	// Only one daemon may write to the database and listen on the control socket
+	instance, err := ClaimInstance(config.DBPath, config.Replace, activated != nil)
+	if err != nil {
+		log.Fatalf("Error: %v", err)
+	}
+	defer instance.Release()
+
+	systemd, err := NewSystemdNotifier()
+	if err != nil {
+		log.Printf("Warning: Failed to set up systemd notifications: %v", err)
+	}
+	defer systemd.Close()
+
+	daemonStatus = NewDaemonStatus(config.DBPath)
+
 	log.Printf("Starting Hyprland activity logger with configuration:")
 	log.Printf("- Terminal Debounce Time: %s", FormatDuration(config.TerminalDebounceTime))
 	log.Printf("- General Debounce Time: %s", FormatDuration(config.GeneralDebounceTime))
 
-	// Optional systray
+	// The tag set before a restart stays active until it is changed or cleared
+	if tag, err := LoadActiveTag(config.DBPath); err != nil {
+		log.Printf("Warning: Failed to restore active tag: %v", err)
+	} else if tag != "" {
+		log.Printf("- Active Tag: %s", tag)
+		setActiveTag(tag)
+	}
+
+	// Optional systray, started once the managers its menu controls exist
 	if config.EnableSystray {
 		log.Printf("- System Tray: Enabled")
 		systrayEnabled = true
-		trayStart, trayEnd := systray.RunWithExternalLoop(systrayOnReady, systrayOnExit)
-		trayStart()
-		defer func() {
-			if trayEnd != nil {
-				trayEnd()
-			}
-		}()
 	} else {
 		log.Printf("- System Tray: Disabled")
 		systrayEnabled = false
@@ -35,38 +59,138 @@ func RunDaemonWithConfig(config LoggerConfig) {
 
 	logEntryChan := make(chan LogEntry, 100)
 	var wg sync.WaitGroup
+	// Monitors writing to logEntryChan, stopped before it is closed
+	var producers sync.WaitGroup
 
 	ctx, cancel := context.WithCancel(context.Background())
 	sigChan := make(chan os.Signal, 1)
 	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
 
 	go func() {
-		if config.EnableSystray {
-			select {
-			case <-sigChan:
-				log.Println("Shutdown signal received, cleaning up...")
-				cancel()
-			case <-quitAppChan:
-				log.Println("Quit requested from tray menu, cleaning up...")
-				cancel()
-			}
-		} else {
-			<-sigChan
+		select {
+		case <-sigChan:
 			log.Println("Shutdown signal received, cleaning up...")
-			cancel()
+		case <-quitAppChan:
+			log.Println("Quit requested from tray menu, cleaning up...")
+		case <-shutdownChan:
+			log.Println("Shutdown requested over the control socket, cleaning up...")
 		}
+		cancel()
 	}()
 
 	log.Printf("- Database: %s", config.DBPath)
+	// Every entry passes through the hub on its way to the database, so that the
+	// monitors can follow the activity as it happens
+	hub := NewActivityHub()
+	dbChan := make(chan LogEntry, 100)
+	go hub.Run(ctx, logEntryChan, dbChan)
+
+	dbReady := make(chan struct{})
+	wg.Add(1)
+	go RunDBLogger(ctx, dbChan, config.DBPath, &wg, dbReady)
+
+	wg.Add(1)
+	go daemonStatus.Run(ctx, &wg, hub)
+
 	wg.Add(1)
-	go RunDBLogger(ctx, logEntryChan, config.DBPath, &wg)
-	
+	go RunEventStream(ctx, &wg, hub, eventStream)
+
+	if systemd != nil {
+		log.Printf("- systemd Notifications: Enabled")
+		if systemd.watchdog > 0 {
+			log.Printf("- Watchdog: every %s", FormatDuration(systemd.watchdog/2))
+		}
+		daemonStatus.OnChange(func() {
+			systemd.SetStatus(systemdStatus(daemonStatus.Snapshot(time.Now())))
+		})
+		wg.Add(1)
+		go systemd.RunWatchdog(ctx, &wg, hub)
+	}
+
+	// A pause from before a restart continues, or ends if it expired in the meantime
+	// The pause state is saved on every change, so its database stays open
+	pauseDB, err := OpenDatabase(config.DBPath)
+	if err != nil {
+		log.Fatalf("Error opening database: %v", err)
+	}
+	defer pauseDB.Close()
+	pauseManager = NewPauseManager(pauseDB, logEntryChan)
+	if err := pauseManager.Restore(time.Now()); err != nil {
+		log.Printf("Warning: Failed to restore the pause state: %v", err)
+	}
+	producers.Add(1)
+	go pauseManager.Run(ctx, &producers)
+
+	if config.Config != nil && config.Config.AutoPause != nil {
+		log.Printf("- Automatic Pauses: %d rules", len(config.Config.AutoPause.Rules))
+		autoPauser = NewAutoPauser(config.Config.AutoPause, pauseManager)
+		producers.Add(1)
+		go autoPauser.Run(ctx, &producers)
+	} else {
+		// Ends an automatic pause restored from before auto_pause was removed
+		pauseManager.SetAuto("", time.Now())
+	}
+
+	notifier, err := NewNotifier()
+	if err != nil {
+		log.Printf("Warning: Desktop notifications are unavailable: %v", err)
+	}
+	defer notifier.Close()
+
+	if config.Config != nil && len(config.Config.Goals) > 0 {
+		log.Printf("- Goals: %d", len(config.Config.Goals))
+		wg.Add(1)
+		go RunGoalMonitor(ctx, &wg, config.DBPath, config.Config, notifier)
+	}
+
+	if config.Config != nil && config.Config.Breaks != nil {
+		breaks := config.Config.Breaks
+		log.Printf("- Break Reminders: after %s of activity", FormatDuration(breaks.after))
+		producers.Add(1)
+		go RunBreakMonitor(ctx, &producers, hub, logEntryChan, breaks, notifier)
+	}
+
+	if config.Config != nil && config.Config.Enforcement != nil && len(config.Config.Enforcement.Rules) > 0 {
+		rules := config.Config.Enforcement
+		if enforcer, err = NewEnforcer(config.Config, notifier); err != nil {
+			log.Printf("Warning: Enforcement is disabled: %v", err)
+		} else {
+			log.Printf("- Enforcement Rules: %d", len(rules.Rules))
+			wg.Add(1)
+			go enforcer.Run(ctx, &wg, hub, config.DBPath)
+		}
+	}
+
+	focusSessions = NewFocusSessionManager(config.Config, logEntryChan, notifier)
+	producers.Add(1)
+	go focusSessions.Run(ctx, &producers, hub)
+
+	if systrayEnabled {
+		trayStart, trayEnd := systray.RunWithExternalLoop(systrayOnReady, systrayOnExit)
+		trayStart()
+		defer func() {
+			if trayEnd != nil {
+				trayEnd()
+			}
+		}()
+	}
+
 	// Start socket listener for external commands (idle signals, pause toggle)
-	if err := StartSocketListener(ctx, &wg, logEntryChan); err != nil {
+	if err := StartSocketListener(ctx, &wg, logEntryChan, activated); err != nil {
 		log.Printf("Warning: Failed to start socket listener: %v", err)
 		log.Println("External control via command line will be unavailable")
 	}
 
+	// The same commands on the session bus, for desktop tools
+	if conn, err := dbus.ConnectSessionBus(); err != nil {
+		log.Printf("Warning: D-Bus interface is unavailable: failed to connect to session bus: %v", err)
+	} else if service, err := NewDBusService(conn, logEntryChan, pauseManager, daemonStatus); err != nil {
+		log.Printf("Warning: D-Bus interface is unavailable: %v", err)
+	} else {
+		producers.Add(1)
+		go service.Run(ctx, &producers, eventStream)
+	}
+
 	client := event.MustClient()
 	defer func() {
 		log.Println("Closing Hyprland event client...")
@@ -76,9 +200,24 @@ func RunDaemonWithConfig(config LoggerConfig) {
 	}()
 
 	handler := NewDebouncedActivityLogger(logEntryChan, config)
+	pauseManager.OnResume(handler.Resumed)
+
+	events := []event.EventType{event.EventActiveWindow}
+	if autoPauser != nil {
+		events = append(events, event.EventWorkspace)
+	}
+
+	// Hyprland queues its events on the connection of the client until they are read,
+	// so the daemon is ready once the database is open and the handler is in place
+	select {
+	case <-dbReady:
+		systemd.SetStatus(systemdStatus(daemonStatus.Snapshot(time.Now())))
+		systemd.Ready()
+	case <-ctx.Done():
+	}
 
-	log.Printf("Subscribing to event: %s", event.EventActiveWindow)
-	err := client.Subscribe(ctx, handler, event.EventActiveWindow)
+	log.Printf("Subscribing to events: %v", events)
+	err = client.Subscribe(ctx, handler, events...)
 	if err != nil {
 		if ctx.Err() == nil {
 			log.Fatalf("Failed to subscribe to Hyprland events: %v", err)
@@ -88,8 +227,10 @@ func RunDaemonWithConfig(config LoggerConfig) {
 	}
 
 	<-ctx.Done()
+	systemd.Stopping()
 
 	log.Println("Main event loop finished. Closing log channel...")
+	producers.Wait()
 	close(logEntryChan)
 
 	log.Println("Waiting for logger to finish...")
@@ -7,7 +7,6 @@ import (
 	"log"
 	"os"
 	"path/filepath"
-	"strings"
 	"sync"
 	"time"
 
@@ -32,12 +31,32 @@ const (
 		);
 		CREATE INDEX IF NOT EXISTS idx_events_timestamp ON events(timestamp);
 		CREATE INDEX IF NOT EXISTS idx_events_window ON events(window_name);
+		CREATE TABLE IF NOT EXISTS manual_entries (
+			id INTEGER PRIMARY KEY,
+			start_time TEXT NOT NULL,
+			end_time TEXT NOT NULL,
+			label TEXT NOT NULL,
+			category TEXT,
+			source TEXT NOT NULL,
+			created_at TEXT NOT NULL,
+			updated_at TEXT NOT NULL,
+			split_from INTEGER REFERENCES manual_entries(id) ON DELETE SET NULL,
+			excluded BOOLEAN NOT NULL DEFAULT 0
+		);
+		CREATE INDEX IF NOT EXISTS idx_manual_entries_start ON manual_entries(start_time);
+	`
+	insertEventSQL = `
+		INSERT INTO events (timestamp, event_type, window_name, window_title, is_idle)
+		VALUES (?, ?, ?, ?, ?)
 	`
 )
 
 type Database struct {
 	db         *sql.DB
 	insertStmt *sql.Stmt
+	// searchEnabled is set when SQLite supports FTS5 and events_fts exists
+	searchEnabled bool
+	searchStmt    *sql.Stmt
 }
 
 func GetDefaultDBPath() string {
@@ -91,24 +110,20 @@ func OpenDatabase(dbPath string) (*Database, error) {
 		}
 	}
 
-	stmt, err := db.Prepare(`
-		INSERT INTO events (timestamp, event_type, window_name, window_title, is_idle) 
-		VALUES (?, ?, ?, ?, ?)
-	`)
+	stmt, err := db.Prepare(insertEventSQL)
 	if err != nil {
 		db.Close()
 		return nil, fmt.Errorf("failed to prepare insert statement: %v", err)
 	}
-
-	return &Database{
-		db:         db,
-		insertStmt: stmt,
-	}, nil
+	return &Database{db: db, insertStmt: stmt}, nil
 }
 
 func (d *Database) Close() error {
-	if d.insertStmt != nil {
-		if err := d.insertStmt.Close(); err != nil {
+	for _, stmt := range []*sql.Stmt{d.insertStmt, d.searchStmt} {
+		if stmt == nil {
+			continue
+		}
+		if err := stmt.Close(); err != nil {
 			log.Printf("Error closing prepared statement: %v", err)
 		}
 	}
@@ -116,7 +131,7 @@ func (d *Database) Close() error {
 }
 
 func (d *Database) InsertLogEntry(entry LogEntry) error {
-	_, err := d.insertStmt.Exec(
+	result, err := d.insertStmt.Exec(
 		entry.Timestamp.Format(time.RFC3339),
 		entry.EventType,
 		entry.EventData.Name,
@@ -126,6 +141,21 @@ func (d *Database) InsertLogEntry(entry LogEntry) error {
 	if err != nil {
 		return fmt.Errorf("failed to insert log entry: %v", err)
 	}
+	return d.indexLogEntry(d.searchStmt, result, entry)
+}
+
+// indexLogEntry adds a freshly inserted event to the search index, if enabled
+func (d *Database) indexLogEntry(stmt *sql.Stmt, result sql.Result, entry LogEntry) error {
+	if !d.searchEnabled || !isSearchIndexed(entry) {
+		return nil
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("failed to get id of log entry: %v", err)
+	}
+	if _, err := stmt.Exec(id, entry.EventData.Name, entry.EventData.Title); err != nil {
+		return fmt.Errorf("failed to index log entry: %v", err)
+	}
 	return nil
 }
 
@@ -178,127 +208,8 @@ func (d *Database) GetEvents(startTime, endTime time.Time) ([]LogEntry, error) {
 	return entries, nil
 }
 
-func (d *Database) GetApplicationSummary(startTime, endTime time.Time) ([]TimeSummary, error) {
-	query := `
-		WITH time_ranges AS (
-			SELECT 
-				window_name,
-				timestamp AS start_time,
-				LEAD(timestamp) OVER (ORDER BY timestamp) AS end_time
-			FROM events
-			WHERE timestamp BETWEEN ? AND ?
-			ORDER BY timestamp
-		)
-		SELECT 
-			window_name,
-			SUM((julianday(end_time) - julianday(start_time)) * 86400) AS duration_seconds
-		FROM time_ranges
-		WHERE end_time IS NOT NULL
-		GROUP BY window_name
-		ORDER BY duration_seconds DESC
-	`
-
-	rows, err := d.db.Query(query,
-		startTime.Format(time.RFC3339),
-		endTime.Format(time.RFC3339),
-	)
-	if err != nil {
-		return nil, fmt.Errorf("query failed: %v", err)
-	}
-	defer rows.Close()
-
-	var summaries []TimeSummary
-	for rows.Next() {
-		var appName string
-		var durationSeconds float64
-
-		if err := rows.Scan(&appName, &durationSeconds); err != nil {
-			return nil, fmt.Errorf("row scan failed: %v", err)
-		}
-
-		summaries = append(summaries, TimeSummary{
-			Name:     appName,
-			Duration: time.Duration(durationSeconds * float64(time.Second)),
-		})
-	}
-
-	if err := rows.Err(); err != nil {
-		return nil, fmt.Errorf("row iteration failed: %v", err)
-	}
-
-	return summaries, nil
-}
-
-// GetKeywordFilteredSummary returns application usage filtered by keywords
-func (d *Database) GetKeywordFilteredSummary(startTime, endTime time.Time, keywords []string) ([]TimeSummary, error) {
-	query := `
-		WITH time_ranges AS (
-			SELECT 
-				window_name,
-				window_title,
-				timestamp AS start_time,
-				LEAD(timestamp) OVER (ORDER BY timestamp) AS end_time
-			FROM events
-			WHERE timestamp BETWEEN ? AND ?
-			AND event_type = ?
-			AND is_idle = 0
-			ORDER BY timestamp
-		)
-		SELECT 
-			window_name,
-			SUM((julianday(end_time) - julianday(start_time)) * 86400) AS duration_seconds
-		FROM time_ranges
-		WHERE end_time IS NOT NULL
-	`
-
-	// If there are keywords, add filtering conditions
-	if len(keywords) > 0 {
-		conditions := make([]string, len(keywords))
-		for i, keyword := range keywords {
-			conditions[i] = fmt.Sprintf("LOWER(window_name) LIKE '%%%s%%' OR LOWER(window_title) LIKE '%%%s%%'", 
-				strings.ToLower(keyword), strings.ToLower(keyword))
-		}
-		query += " AND (" + strings.Join(conditions, " OR ") + ")"
-	}
-
-	query += `
-		GROUP BY window_name
-		ORDER BY duration_seconds DESC
-	`
-
-	rows, err := d.db.Query(query,
-		startTime.Format(time.RFC3339),
-		endTime.Format(time.RFC3339),
-		string(event.EventActiveWindow),
-	)
-	if err != nil {
-		return nil, fmt.Errorf("query failed: %v", err)
-	}
-	defer rows.Close()
-
-	var summaries []TimeSummary
-	for rows.Next() {
-		var appName string
-		var durationSeconds float64
-
-		if err := rows.Scan(&appName, &durationSeconds); err != nil {
-			return nil, fmt.Errorf("row scan failed: %v", err)
-		}
-
-		summaries = append(summaries, TimeSummary{
-			Name:     appName,
-			Duration: time.Duration(durationSeconds * float64(time.Second)),
-		})
-	}
-
-	if err := rows.Err(); err != nil {
-		return nil, fmt.Errorf("row iteration failed: %v", err)
-	}
-
-	return summaries, nil
-}
-
-func RunDBLogger(ctx context.Context, logChan <-chan LogEntry, dbPath string, wg *sync.WaitGroup) {
+// RunDBLogger writes the entries to the database, closing ready once it is open
+func RunDBLogger(ctx context.Context, logChan <-chan LogEntry, dbPath string, wg *sync.WaitGroup, ready chan<- struct{}) {
 	defer wg.Done()
 
 	db, err := OpenDatabase(dbPath)
@@ -315,27 +226,74 @@ func RunDBLogger(ctx context.Context, logChan <-chan LogEntry, dbPath string, wg
 
 	log.Printf("SQLite Database Logger started with DB: %s", dbPath)
 
+	if err := db.EnableSearch(); err != nil {
+		log.Printf("Error setting up the search index: %v", err)
+	}
+	if db.searchEnabled {
+		if n, err := db.BackfillSearchIndex(); err != nil {
+			log.Printf("Error backfilling search index: %v", err)
+		} else if n > 0 {
+			log.Printf("Added %d existing events to the search index", n)
+		}
+	} else {
+		log.Println("SQLite was built without FTS5, window titles will not be indexed for search")
+	}
+
 	tx, err := db.db.Begin()
 	if err != nil {
 		log.Fatalf("Failed to begin transaction: %v", err)
 		return
 	}
 
-	txStmt, err := tx.Prepare(`
-		INSERT INTO events (timestamp, event_type, window_name, window_title, is_idle)
-		VALUES (?, ?, ?, ?, ?)
-	`)
+	txStmt, err := tx.Prepare(insertEventSQL)
 	if err != nil {
 		log.Fatalf("Failed to prepare transaction statement: %v", err)
 		tx.Rollback()
 		return
 	}
+	var txSearchStmt *sql.Stmt
+	if db.searchEnabled {
+		txSearchStmt = tx.Stmt(db.searchStmt)
+	}
 
 	insertCount := 0
 	commitThreshold := 100
 	lastCommit := time.Now()
 	commitInterval := 5 * time.Second
 
+	// Commit if we've reached the threshold or time interval
+	commitIfDue := func(now time.Time) {
+		if insertCount == 0 || (insertCount < commitThreshold && now.Sub(lastCommit) < commitInterval) {
+			return
+		}
+		if err := tx.Commit(); err != nil {
+			log.Printf("Error committing transaction: %v", err)
+			daemonStatus.RecordError(fmt.Errorf("failed to commit events: %v", err))
+			tx.Rollback()
+			return
+		}
+
+		tx, err = db.db.Begin()
+		if err != nil {
+			log.Fatalf("Failed to begin new transaction: %v", err)
+		}
+		txStmt, err = tx.Prepare(insertEventSQL)
+		if err != nil {
+			tx.Rollback()
+			log.Fatalf("Failed to prepare new transaction statement: %v", err)
+		}
+		if db.searchEnabled {
+			txSearchStmt = tx.Stmt(db.searchStmt)
+		}
+
+		insertCount = 0
+		daemonStatus.pending.Store(0)
+		lastCommit = now
+	}
+	commitTicker := time.NewTicker(commitInterval)
+	defer commitTicker.Stop()
+	close(ready)
+
 	for {
 		select {
 		case entry, ok := <-logChan:
@@ -350,7 +308,7 @@ func RunDBLogger(ctx context.Context, logChan <-chan LogEntry, dbPath string, wg
 				return
 			}
 
-			_, err := txStmt.Exec(
+			result, err := txStmt.Exec(
 				entry.Timestamp.Format(time.RFC3339),
 				entry.EventType,
 				entry.EventData.Name,
@@ -359,38 +317,23 @@ func RunDBLogger(ctx context.Context, logChan <-chan LogEntry, dbPath string, wg
 			)
 			if err != nil {
 				log.Printf("Error inserting entry into database: %v", err)
+				daemonStatus.RecordError(fmt.Errorf("failed to write event: %v", err))
 				continue
 			}
+			if err := db.indexLogEntry(txSearchStmt, result, entry); err != nil {
+				log.Printf("Error updating search index: %v", err)
+				daemonStatus.RecordError(fmt.Errorf("failed to update search index: %v", err))
+			}
 
 			insertCount++
-
-			// Commit if we've reached the threshold or time interval
-			now := time.Now()
-			if insertCount >= commitThreshold || now.Sub(lastCommit) >= commitInterval {
-				if err := tx.Commit(); err != nil {
-					log.Printf("Error committing transaction: %v", err)
-					tx.Rollback()
-				} else {
-					tx, err = db.db.Begin()
-					if err != nil {
-						log.Fatalf("Failed to begin new transaction: %v", err)
-						return
-					}
-
-					txStmt, err = tx.Prepare(`
-						INSERT INTO events (timestamp, event_type, window_name, window_title, is_idle)
-						VALUES (?, ?, ?, ?, ?)
-					`)
-					if err != nil {
-						log.Fatalf("Failed to prepare new transaction statement: %v", err)
-						tx.Rollback()
-						return
-					}
-
-					insertCount = 0
-					lastCommit = now
-				}
-			}
+			daemonStatus.written.Add(1)
+			daemonStatus.pending.Store(int64(insertCount))
+			commitIfDue(time.Now())
+
+		case now := <-commitTicker.C:
+			// Rows written just before a quiet period would otherwise stay
+			// uncommitted, and the database locked, until the next event
+			commitIfDue(now)
 
 		case <-ctx.Done():
 			log.Println("Context canceled, database logger flushing and exiting...")
@@ -0,0 +1,256 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/godbus/dbus/v5"
+	"github.com/godbus/dbus/v5/introspect"
+	"github.com/godbus/dbus/v5/prop"
+)
+
+const (
+	trackerBusName   = "org.hyprtracker.Tracker"
+	trackerPath      = "/org/hyprtracker/Tracker"
+	trackerInterface = "org.hyprtracker.Tracker"
+
+	errUnavailableName = trackerInterface + ".Error.Unavailable"
+)
+
+// trackerObject holds the methods of the org.hyprtracker.Tracker interface. They run
+// the commands of the control socket, so both behave alike.
+type trackerObject struct {
+	logChan chan<- LogEntry
+}
+
+// DBusAppSummary is an application with its active time, returned by GetSummary
+type DBusAppSummary struct {
+	App     string
+	Seconds int64
+}
+
+func (t trackerObject) dispatch(command string, args any) (any, *dbus.Error) {
+	request := Request{Command: command}
+	if args != nil {
+		data, err := json.Marshal(args)
+		if err != nil {
+			return nil, dbus.MakeFailedError(err)
+		}
+		request.Args = data
+	}
+	result, err := dispatchCommand(request, t.logChan)
+	if err != nil {
+		return nil, dbusError(err)
+	}
+	return result, nil
+}
+
+// dbusError translates an error of a command into a D-Bus error
+func dbusError(err error) *dbus.Error {
+	var protocolErr *ProtocolError
+	if !errors.As(err, &protocolErr) {
+		return dbus.MakeFailedError(err)
+	}
+	name := "org.freedesktop.DBus.Error.Failed"
+	switch protocolErr.Code {
+	case ErrInvalidArgument:
+		name = "org.freedesktop.DBus.Error.InvalidArgs"
+	case ErrUnavailable:
+		name = errUnavailableName
+	}
+	return dbus.NewError(name, []any{protocolErr.Message})
+}
+
+// Pause pauses the tracking for a duration such as "30m", or until it is resumed if
+// the duration is empty
+func (t trackerObject) Pause(duration, reason string) *dbus.Error {
+	_, err := t.dispatch("pause", PauseArgs{For: duration, Reason: reason})
+	return err
+}
+
+func (t trackerObject) Resume() *dbus.Error {
+	_, err := t.dispatch("resume", nil)
+	return err
+}
+
+// Tag tags the current and following activity, or clears the tag if it is empty
+func (t trackerObject) Tag(tag string) *dbus.Error {
+	_, err := t.dispatch("tag", TagArgs{Tag: tag})
+	return err
+}
+
+func (t trackerObject) IdleStart() *dbus.Error {
+	_, err := t.dispatch("idle", AwayArgs{Action: "start"})
+	return err
+}
+
+func (t trackerObject) IdleEnd() *dbus.Error {
+	_, err := t.dispatch("idle", AwayArgs{Action: "end"})
+	return err
+}
+
+// GetStatus returns the fields of the status command that are set
+func (t trackerObject) GetStatus() (map[string]dbus.Variant, *dbus.Error) {
+	result, err := t.dispatch("status", nil)
+	if err != nil {
+		return nil, err
+	}
+	s := result.(StatusResult)
+	status := map[string]dbus.Variant{
+		"State":              dbus.MakeVariant(s.State()),
+		"Paused":             dbus.MakeVariant(s.Paused),
+		"Idle":               dbus.MakeVariant(s.Idle),
+		"ActiveTodaySeconds": dbus.MakeVariant(s.ActiveSeconds),
+		"UptimeSeconds":      dbus.MakeVariant(s.UptimeSeconds),
+		"Pid":                dbus.MakeVariant(int32(s.Pid)),
+		"DBPath":             dbus.MakeVariant(s.DBPath),
+	}
+	if s.Paused {
+		status["PauseReason"] = dbus.MakeVariant(s.PauseReason)
+		status["PauseAuto"] = dbus.MakeVariant(s.PauseAuto)
+		if s.PausedUntil != nil {
+			status["PausedUntil"] = dbus.MakeVariant(s.PausedUntil.Unix())
+		}
+	}
+	if s.App != "" {
+		status["App"] = dbus.MakeVariant(s.App)
+		status["Title"] = dbus.MakeVariant(s.Title)
+		status["ElapsedSeconds"] = dbus.MakeVariant(s.ElapsedSeconds)
+	}
+	if s.Tag != "" {
+		status["Tag"] = dbus.MakeVariant(s.Tag)
+	}
+	if s.LastError != "" {
+		status["LastError"] = dbus.MakeVariant(s.LastError)
+	}
+	return status, nil
+}
+
+// GetSummary returns the total active time and the time per application within a
+// range such as "today", "week" or "2026-10-01..2026-11-01"
+func (t trackerObject) GetSummary(timeRange string) (int64, []DBusAppSummary, *dbus.Error) {
+	result, err := t.dispatch("summary", SummaryArgs{Range: timeRange})
+	if err != nil {
+		return 0, nil, err
+	}
+	summary := result.(SummaryResult)
+	apps := make([]DBusAppSummary, 0, len(summary.Apps))
+	for _, app := range summary.Apps {
+		apps = append(apps, DBusAppSummary{App: app.App, Seconds: app.Seconds})
+	}
+	return summary.TotalSeconds, apps, nil
+}
+
+// DBusService exports the tracker on the session bus as org.hyprtracker.Tracker, with
+// the Paused and CurrentApp properties and a FocusChanged signal
+type DBusService struct {
+	conn   *dbus.Conn
+	props  *prop.Properties
+	pauses *PauseManager
+	status *DaemonStatus
+}
+
+// NewDBusService exports the tracker object on conn, a connection to the session bus,
+// and takes the bus name. The connection is closed if this fails, or when Run returns.
+func NewDBusService(conn *dbus.Conn, logChan chan<- LogEntry, pauses *PauseManager, status *DaemonStatus) (*DBusService, error) {
+	s := &DBusService{conn: conn, pauses: pauses, status: status}
+	if err := s.export(trackerObject{logChan: logChan}); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	reply, err := conn.RequestName(trackerBusName, dbus.NameFlagDoNotQueue)
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to request the name %s: %v", trackerBusName, err)
+	}
+	if reply != dbus.RequestNameReplyPrimaryOwner {
+		conn.Close()
+		return nil, fmt.Errorf("the name %s is already taken, by the daemon of another Hyprland instance?", trackerBusName)
+	}
+	return s, nil
+}
+
+func (s *DBusService) export(tracker trackerObject) error {
+	if err := s.conn.Export(tracker, trackerPath, trackerInterface); err != nil {
+		return fmt.Errorf("failed to export the tracker: %v", err)
+	}
+
+	var err error
+	s.props, err = prop.Export(s.conn, trackerPath, prop.Map{
+		trackerInterface: {
+			"Paused":     {Value: s.pauses.State().Paused, Emit: prop.EmitTrue},
+			"CurrentApp": {Value: s.status.Snapshot(time.Now()).App, Emit: prop.EmitTrue},
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to export the tracker properties: %v", err)
+	}
+
+	node := &introspect.Node{
+		Name: trackerPath,
+		Interfaces: []introspect.Interface{
+			introspect.IntrospectData,
+			prop.IntrospectData,
+			{
+				Name:       trackerInterface,
+				Methods:    introspect.Methods(tracker),
+				Properties: s.props.Introspection(trackerInterface),
+				Signals: []introspect.Signal{{
+					Name: "FocusChanged",
+					Args: []introspect.Arg{{Name: "app", Type: "s"}, {Name: "title", Type: "s"}},
+				}},
+			},
+		},
+	}
+	if err := s.conn.Export(introspect.NewIntrospectable(node), trackerPath, "org.freedesktop.DBus.Introspectable"); err != nil {
+		return fmt.Errorf("failed to export the introspection data: %v", err)
+	}
+	return nil
+}
+
+// setProperty changes a property, announcing it with PropertiesChanged only if its
+// value changed
+func (s *DBusService) setProperty(name string, value any) {
+	if s.props.GetMust(trackerInterface, name) == value {
+		return
+	}
+	s.props.SetMust(trackerInterface, name, value)
+}
+
+// Run keeps the properties up to date and emits FocusChanged until the context is
+// canceled, then leaves the bus
+func (s *DBusService) Run(ctx context.Context, wg *sync.WaitGroup, stream *EventStream) {
+	defer wg.Done()
+	defer s.conn.Close()
+
+	sub, err := stream.Subscribe([]string{StreamFocus, StreamPause}, subscriptionBuffer)
+	if err != nil {
+		log.Printf("Error following the activity for D-Bus: %v", err)
+		return
+	}
+	defer stream.Unsubscribe(sub)
+	log.Printf("D-Bus service %s started", trackerBusName)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case e := <-sub.C:
+			switch e.Event {
+			case StreamFocus:
+				s.setProperty("CurrentApp", e.App)
+				if err := s.conn.Emit(trackerPath, trackerInterface+".FocusChanged", e.App, e.Title); err != nil {
+					log.Printf("Error emitting FocusChanged: %v", err)
+				}
+			case StreamPause:
+				s.setProperty("Paused", s.pauses.State().Paused)
+			}
+		}
+	}
+}
@@ -0,0 +1,246 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/godbus/dbus/v5"
+	"github.com/thiagokokada/hyprland-go/event"
+)
+
+const signalTimeout = 5 * time.Second
+
+// startSessionBus runs a private session bus for the test and returns its address
+func startSessionBus(t *testing.T) string {
+	t.Helper()
+	if _, err := exec.LookPath("dbus-daemon"); err != nil {
+		t.Skip("dbus-daemon is not installed")
+	}
+	cmd := exec.Command("dbus-daemon", "--session", "--nofork", "--print-address=1")
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		t.Fatalf("dbus-daemon stdout: %v", err)
+	}
+	if err := cmd.Start(); err != nil {
+		t.Fatalf("start dbus-daemon: %v", err)
+	}
+	t.Cleanup(func() {
+		cmd.Process.Kill()
+		cmd.Wait()
+	})
+	address, err := bufio.NewReader(stdout).ReadString('\n')
+	if err != nil {
+		t.Fatalf("read the dbus-daemon address: %v", err)
+	}
+	return strings.TrimSpace(address)
+}
+
+func connectBus(t *testing.T, address string) *dbus.Conn {
+	t.Helper()
+	conn, err := dbus.Connect(address)
+	if err != nil {
+		t.Fatalf("connect to %s: %v", address, err)
+	}
+	return conn
+}
+
+// waitSignal returns the first signal with the given name for which match is true,
+// skipping the others
+func waitSignal(t *testing.T, signals <-chan *dbus.Signal, name string, match func(*dbus.Signal) bool) *dbus.Signal {
+	t.Helper()
+	timeout := time.After(signalTimeout)
+	for {
+		select {
+		case signal := <-signals:
+			if signal.Name == name && match(signal) {
+				return signal
+			}
+		case <-timeout:
+			t.Fatalf("no %s signal within %s", name, signalTimeout)
+			return nil
+		}
+	}
+}
+
+// propertyChanged matches a PropertiesChanged signal setting the property to value
+func propertyChanged(property string, value any) func(*dbus.Signal) bool {
+	return func(signal *dbus.Signal) bool {
+		if len(signal.Body) < 2 {
+			return false
+		}
+		changed, ok := signal.Body[1].(map[string]dbus.Variant)
+		if !ok {
+			return false
+		}
+		v, ok := changed[property]
+		return ok && v.Value() == value
+	}
+}
+
+func TestDBusService(t *testing.T) {
+	address := startSessionBus(t)
+
+	// Two visits recorded before the daemon started, for GetSummary
+	dbPath := filepath.Join(t.TempDir(), "hyprtracker.db")
+	db, err := OpenDatabase(dbPath)
+	if err != nil {
+		t.Fatalf("OpenDatabase: %v", err)
+	}
+	defer db.Close()
+	now := time.Now().Truncate(time.Second)
+	for _, entry := range []LogEntry{
+		{Timestamp: now.Add(-10 * time.Minute), EventType: string(event.EventActiveWindow), EventData: event.ActiveWindow{Name: "kitty", Title: "vim"}},
+		{Timestamp: now.Add(-5 * time.Minute), EventType: string(event.EventActiveWindow), EventData: event.ActiveWindow{Name: "firefox", Title: "Docs"}},
+		{Timestamp: now.Add(-4 * time.Minute), EventType: "idle_start", IsIdle: true},
+	} {
+		if err := db.InsertLogEntry(entry); err != nil {
+			t.Fatalf("InsertLogEntry: %v", err)
+		}
+	}
+
+	// The activity pipeline of the daemon, without the database logger
+	ctx, cancel := context.WithCancel(context.Background())
+	var wg sync.WaitGroup
+	defer func() {
+		cancel()
+		wg.Wait()
+	}()
+	logChan, dbChan := make(chan LogEntry, 100), make(chan LogEntry, 100)
+	hub := NewActivityHub()
+	go hub.Run(ctx, logChan, dbChan)
+	go func() {
+		for range dbChan {
+		}
+	}()
+	stream := NewEventStream()
+	wg.Add(2)
+	go RunEventStream(ctx, &wg, hub, stream)
+
+	// The commands behind the methods use the daemon's pause manager and status
+	oldPauses, oldStatus := pauseManager, daemonStatus
+	pauseManager, daemonStatus = NewPauseManager(db, logChan), NewDaemonStatus(dbPath)
+	defer func() {
+		pauseManager, daemonStatus = oldPauses, oldStatus
+		setActiveTag("")
+	}()
+	go daemonStatus.Run(ctx, &wg, hub)
+
+	service, err := NewDBusService(connectBus(t, address), logChan, pauseManager, daemonStatus)
+	if err != nil {
+		t.Fatalf("NewDBusService: %v", err)
+	}
+	wg.Add(1)
+	go service.Run(ctx, &wg, stream)
+	// Events published before Run subscribes would be missed
+	for deadline := time.Now().Add(signalTimeout); ; {
+		stream.mu.Lock()
+		subscribers := len(stream.subscribers)
+		stream.mu.Unlock()
+		if subscribers == 1 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("the service did not subscribe to the event stream")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	client := connectBus(t, address)
+	defer client.Close()
+	if err := client.AddMatchSignal(dbus.WithMatchObjectPath(trackerPath)); err != nil {
+		t.Fatalf("AddMatchSignal: %v", err)
+	}
+	signals := make(chan *dbus.Signal, 50)
+	client.Signal(signals)
+	tracker := client.Object(trackerBusName, trackerPath)
+
+	getStatus := func() map[string]dbus.Variant {
+		t.Helper()
+		var status map[string]dbus.Variant
+		if err := tracker.Call(trackerInterface+".GetStatus", 0).Store(&status); err != nil {
+			t.Fatalf("GetStatus: %v", err)
+		}
+		return status
+	}
+
+	t.Run("Pause", func(t *testing.T) {
+		if err := tracker.Call(trackerInterface+".Pause", 0, "30m", "lunch").Err; err != nil {
+			t.Fatalf("Pause: %v", err)
+		}
+		waitSignal(t, signals, "org.freedesktop.DBus.Properties.PropertiesChanged", propertyChanged("Paused", true))
+		status := getStatus()
+		if status["Paused"].Value() != true || status["PauseReason"].Value() != "lunch" {
+			t.Errorf("status after Pause = %v, want paused for lunch", status)
+		}
+		if _, ok := status["PausedUntil"]; !ok {
+			t.Errorf("status after Pause has no PausedUntil: %v", status)
+		}
+	})
+
+	t.Run("Resume", func(t *testing.T) {
+		if err := tracker.Call(trackerInterface+".Resume", 0).Err; err != nil {
+			t.Fatalf("Resume: %v", err)
+		}
+		waitSignal(t, signals, "org.freedesktop.DBus.Properties.PropertiesChanged", propertyChanged("Paused", false))
+		if status := getStatus(); status["Paused"].Value() != false {
+			t.Errorf("status after Resume = %v, want not paused", status)
+		}
+	})
+
+	t.Run("InvalidPause", func(t *testing.T) {
+		err := tracker.Call(trackerInterface+".Pause", 0, "soon", "").Err
+		if dbusErr, ok := err.(dbus.Error); !ok || dbusErr.Name != "org.freedesktop.DBus.Error.InvalidArgs" {
+			t.Errorf("Pause with an invalid duration: err = %v, want InvalidArgs", err)
+		}
+	})
+
+	t.Run("Tag", func(t *testing.T) {
+		if err := tracker.Call(trackerInterface+".Tag", 0, "ticket-42").Err; err != nil {
+			t.Fatalf("Tag: %v", err)
+		}
+		if status := getStatus(); status["Tag"].Value() != "ticket-42" {
+			t.Errorf("status after Tag = %v, want tag ticket-42", status)
+		}
+	})
+
+	t.Run("FocusChanged", func(t *testing.T) {
+		logChan <- LogEntry{Timestamp: time.Now(), EventType: string(event.EventActiveWindow),
+			EventData: event.ActiveWindow{Name: "code", Title: "main.go"}}
+		// The property is updated before the signal is emitted
+		waitSignal(t, signals, "org.freedesktop.DBus.Properties.PropertiesChanged", propertyChanged("CurrentApp", "code"))
+		waitSignal(t, signals, trackerInterface+".FocusChanged", func(signal *dbus.Signal) bool {
+			return len(signal.Body) == 2 && signal.Body[0] == "code" && signal.Body[1] == "main.go"
+		})
+
+		var app dbus.Variant
+		if err := tracker.Call("org.freedesktop.DBus.Properties.Get", 0, trackerInterface, "CurrentApp").Store(&app); err != nil {
+			t.Fatalf("Get CurrentApp: %v", err)
+		}
+		if app.Value() != "code" {
+			t.Errorf("CurrentApp = %v, want code", app.Value())
+		}
+	})
+
+	t.Run("GetSummary", func(t *testing.T) {
+		var total int64
+		var apps []DBusAppSummary
+		if err := tracker.Call(trackerInterface+".GetSummary", 0, "all").Store(&total, &apps); err != nil {
+			t.Fatalf("GetSummary: %v", err)
+		}
+		want := []DBusAppSummary{{App: "kitty", Seconds: 300}, {App: "firefox", Seconds: 60}}
+		if total != 360 || len(apps) != len(want) || apps[0] != want[0] || apps[1] != want[1] {
+			t.Errorf("GetSummary = %d, %v, want 360, %v", total, apps, want)
+		}
+
+		err := tracker.Call(trackerInterface+".GetSummary", 0, "2026-10-31..2026-10-01").Err
+		if dbusErr, ok := err.(dbus.Error); !ok || dbusErr.Name != "org.freedesktop.DBus.Error.InvalidArgs" {
+			t.Errorf("GetSummary with an invalid range: err = %v, want InvalidArgs", err)
+		}
+	})
+}
@@ -0,0 +1,432 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/thiagokokada/hyprland-go"
+	"github.com/thiagokokada/hyprland-go/event"
+	"github.com/thiagokokada/hyprland-go/helpers"
+)
+
+const (
+	EnforceWarn          = "warn"
+	EnforceMove          = "move"
+	EnforceFocusPrevious = "focus-previous"
+
+	DefaultEnforcementCooldown = time.Minute
+	DefaultEnforcementOverride = 15 * time.Minute
+	DefaultBlockedWorkspace    = "special:blocked"
+	enforcementCheckInterval   = 30 * time.Second
+)
+
+// EnforcementConfig makes the daemon act on distracting windows instead of only
+// reporting them
+type EnforcementConfig struct {
+	Rules []EnforcementRule `json:"rules"`
+	// Cooldown is the shortest time between two actions of the same rule
+	Cooldown string `json:"cooldown"`
+	// Override is how long "override" on the socket suspends the rules by default
+	Override string `json:"override"`
+	// DispatchSocket replaces the Hyprland request socket, e.g. with a fake one for testing
+	DispatchSocket string `json:"dispatch_socket"`
+
+	cooldown time.Duration
+	override time.Duration
+}
+
+// EnforcementRule blocks the windows matching its patterns, which work like project
+// patterns, during its hours or once their time today exceeds the limit. A rule
+// without hours and limit always applies.
+type EnforcementRule struct {
+	Name  string   `json:"name"`
+	Match []string `json:"match"`
+	// Hours is a daily time span such as "09:00-17:00", on Days if set
+	Hours string   `json:"hours"`
+	Days  []string `json:"days"`
+	// Limit is the time allowed per day before the rule applies, e.g. "45m"
+	Limit string `json:"limit"`
+	// Action is "warn" (default), "move" to send the window to Workspace, or
+	// "focus-previous" to focus the last window not blocked
+	Action    string `json:"action"`
+	Workspace string `json:"workspace"`
+
+	hours clockRange
+	days  map[time.Weekday]bool
+	limit time.Duration
+}
+
+// validate parses the settings and fills in the defaults
+func (e *EnforcementConfig) validate() error {
+	var err error
+	if e.cooldown, err = parseConfigDuration("enforcement cooldown", e.Cooldown, DefaultEnforcementCooldown); err != nil {
+		return err
+	}
+	if e.override, err = parseConfigDuration("enforcement override", e.Override, DefaultEnforcementOverride); err != nil {
+		return err
+	}
+	names := make(map[string]bool)
+	for i := range e.Rules {
+		if err := e.Rules[i].validate(); err != nil {
+			return err
+		}
+		if names[e.Rules[i].Name] {
+			return fmt.Errorf("duplicate enforcement rule %s", e.Rules[i].Name)
+		}
+		names[e.Rules[i].Name] = true
+	}
+	return nil
+}
+
+func (r *EnforcementRule) validate() error {
+	if r.Name == "" {
+		return fmt.Errorf("enforcement rule without a name")
+	}
+	if len(r.Match) == 0 {
+		return fmt.Errorf("enforcement rule %s has no match patterns", r.Name)
+	}
+
+	var err error
+	if r.Hours != "" {
+		if r.hours, err = parseClockRange(r.Hours); err != nil {
+			return fmt.Errorf("%v in enforcement rule %s", err, r.Name)
+		}
+	}
+	if r.days, err = parseDays(r.Days); err != nil {
+		return fmt.Errorf("%v in enforcement rule %s", err, r.Name)
+	}
+	if r.limit, err = parseConfigDuration("limit in enforcement rule "+r.Name, r.Limit, 0); err != nil {
+		return err
+	}
+
+	switch r.Action {
+	case "":
+		r.Action = EnforceWarn
+	case EnforceWarn, EnforceMove, EnforceFocusPrevious:
+	default:
+		return fmt.Errorf("invalid action %q in enforcement rule %s (must be 'warn', 'move' or 'focus-previous')", r.Action, r.Name)
+	}
+	if r.Workspace == "" {
+		r.Workspace = DefaultBlockedWorkspace
+	}
+	return nil
+}
+
+// parseClockOffset parses "HH:MM" into the time since midnight
+func parseClockOffset(s string) (time.Duration, error) {
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, err
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
+
+// clockRange is a daily time span such as "09:00-17:00", which may wrap past midnight
+type clockRange struct {
+	from, to time.Duration
+}
+
+func parseClockRange(s string) (clockRange, error) {
+	from, to, ok := strings.Cut(s, "-")
+	var r clockRange
+	var fromErr, toErr error
+	r.from, fromErr = parseClockOffset(strings.TrimSpace(from))
+	r.to, toErr = parseClockOffset(strings.TrimSpace(to))
+	if !ok || fromErr != nil || toErr != nil || r.from == r.to {
+		return clockRange{}, fmt.Errorf("invalid hours %q (expected e.g. \"09:00-17:00\")", s)
+	}
+	return r, nil
+}
+
+// contains reports whether t falls within the span
+func (r clockRange) contains(t time.Time) bool {
+	t = t.Local()
+	offset := t.Sub(BucketStart(t, BucketDay))
+	if r.from < r.to {
+		return offset >= r.from && offset < r.to
+	}
+	return offset >= r.from || offset < r.to
+}
+
+// inHours reports whether t falls within the rule's hours
+func (r *EnforcementRule) inHours(t time.Time) bool {
+	return r.Hours != "" && r.hours.contains(t)
+}
+
+// Applies reports whether the rule blocks its windows at t, given their time today
+func (r *EnforcementRule) Applies(t time.Time, used time.Duration) bool {
+	if r.days != nil && !r.days[t.Local().Weekday()] {
+		return false
+	}
+	if r.Hours == "" && r.limit == 0 {
+		return true
+	}
+	return r.inHours(t) || (r.limit > 0 && used > r.limit)
+}
+
+// Describe returns why the rule applies, for notifications
+func (r *EnforcementRule) Describe(t time.Time, used time.Duration) string {
+	switch {
+	case r.inHours(t):
+		return fmt.Sprintf("%s is blocked during %s", r.Name, r.Hours)
+	case r.limit > 0 && used > r.limit:
+		return fmt.Sprintf("%s is over its daily limit (%s of %s)", r.Name, FormatDuration(used), FormatDuration(r.limit))
+	default:
+		return r.Name + " is blocked"
+	}
+}
+
+func (r *EnforcementRule) matches(iv Interval, config *Config) bool {
+	for _, pattern := range r.Match {
+		if config.MatchIntervalPattern(pattern, iv) {
+			return true
+		}
+	}
+	return false
+}
+
+// Enforcer follows the focused window and dispatches the actions of the rules that
+// block it
+type Enforcer struct {
+	config   *Config
+	rules    *EnforcementConfig
+	client   *hyprland.RequestClient
+	notifier *Notifier
+
+	mu            sync.Mutex
+	overrideUntil time.Time
+	lastActed     map[string]time.Time
+	// used is the time spent on each rule's windows today
+	used    map[string]time.Duration
+	usedDay time.Time
+	// focused is the focused window since the given time, zero while away
+	focused        Interval
+	focusedAddress string
+	since          time.Time
+	away           map[string]bool
+	// previous is the address of the last focused window that was not blocked
+	previous string
+}
+
+// NewEnforcer connects to the dispatch socket of the rules, or the one of the running
+// Hyprland instance
+func NewEnforcer(config *Config, notifier *Notifier) (*Enforcer, error) {
+	rules := config.Enforcement
+	socket := rules.DispatchSocket
+	if socket == "" {
+		var err error
+		if socket, err = helpers.GetSocket(helpers.RequestSocket); err != nil {
+			return nil, fmt.Errorf("failed to find the Hyprland request socket: %v", err)
+		}
+	}
+	return &Enforcer{
+		config:    config,
+		rules:     rules,
+		client:    hyprland.NewClient(socket),
+		notifier:  notifier,
+		lastActed: make(map[string]time.Time),
+		used:      make(map[string]time.Duration),
+		away:      make(map[string]bool),
+	}, nil
+}
+
+// Override suspends the rules for d, or lifts the override if d is zero, and returns
+// the end of the override
+func (e *Enforcer) Override(d time.Duration) time.Time {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if d <= 0 {
+		e.overrideUntil = time.Time{}
+		log.Println("Enforcement override lifted")
+		return e.overrideUntil
+	}
+	e.overrideUntil = time.Now().Add(d)
+	log.Printf("Enforcement overridden until %s", e.overrideUntil.Format("15:04:05"))
+	return e.overrideUntil
+}
+
+// DefaultOverride is the override length used when none is given
+func (e *Enforcer) DefaultOverride() time.Duration {
+	return e.rules.override
+}
+
+// seed loads the time spent today on each rule's windows from the database
+func (e *Enforcer) seed(dbPath string, now time.Time) {
+	db, err := OpenDatabase(dbPath)
+	if err != nil {
+		log.Printf("Error loading today's activity for enforcement: %v", err)
+		return
+	}
+	defer db.Close()
+
+	day := BucketStart(now, BucketDay)
+	intervals, err := db.GetIntervals(day, now, now)
+	if err != nil {
+		log.Printf("Error loading today's activity for enforcement: %v", err)
+		return
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.usedDay = day
+	for _, iv := range intervals {
+		for i := range e.rules.Rules {
+			if e.rules.Rules[i].matches(iv, e.config) {
+				e.used[e.rules.Rules[i].Name] += iv.Duration()
+			}
+		}
+	}
+}
+
+// account adds the time the focused window has been focused to its rules
+func (e *Enforcer) account(now time.Time) {
+	if day := BucketStart(now, BucketDay); !day.Equal(e.usedDay) {
+		e.used = make(map[string]time.Duration)
+		e.usedDay = day
+	}
+	if e.since.IsZero() || e.focused.App == "" || !now.After(e.since) {
+		return
+	}
+	for i := range e.rules.Rules {
+		if e.rules.Rules[i].matches(e.focused, e.config) {
+			e.used[e.rules.Rules[i].Name] += now.Sub(e.since)
+		}
+	}
+	e.since = now
+}
+
+// blockedBy returns the first rule blocking the focused window at now
+func (e *Enforcer) blockedBy(now time.Time) *EnforcementRule {
+	if e.focused.App == "" {
+		return nil
+	}
+	for i := range e.rules.Rules {
+		rule := &e.rules.Rules[i]
+		if rule.matches(e.focused, e.config) && rule.Applies(now, e.used[rule.Name]) {
+			return rule
+		}
+	}
+	return nil
+}
+
+// activeAddress asks Hyprland for the address of the focused window, which the
+// activewindow event does not carry. It is empty if the window changed meanwhile.
+func (e *Enforcer) activeAddress(entry LogEntry) string {
+	if entry.EventType != string(event.EventActiveWindow) {
+		return ""
+	}
+	window, err := e.client.ActiveWindow()
+	if err != nil {
+		log.Printf("Error getting the active window for enforcement: %v", err)
+		return ""
+	}
+	if window.Class != entry.EventData.Name {
+		return ""
+	}
+	return window.Address
+}
+
+// observe follows an entry of the activity stream. address is the address of the
+// window focused by an activewindow entry, if known.
+func (e *Enforcer) observe(entry LogEntry, address string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if kind, start, ok := awayEventKind(entry.EventType); ok {
+		e.account(entry.Timestamp)
+		if start {
+			e.away[kind] = true
+			e.since = time.Time{}
+		} else {
+			delete(e.away, kind)
+			if len(e.away) == 0 {
+				e.since = entry.Timestamp
+			}
+		}
+		return
+	}
+
+	switch entry.EventType {
+	case TagEventType:
+		e.account(entry.Timestamp)
+		e.focused.Tag = entry.EventData.Title
+	case string(event.EventActiveWindow):
+		e.account(entry.Timestamp)
+		if e.focusedAddress != "" && e.blockedBy(entry.Timestamp) == nil {
+			e.previous = e.focusedAddress
+		}
+		e.focused.App, e.focused.Title = entry.EventData.Name, entry.EventData.Title
+		e.focusedAddress = address
+		if len(e.away) == 0 {
+			e.since = entry.Timestamp
+		}
+	}
+}
+
+// check acts on the focused window if a rule blocks it and its cooldown is over.
+// Nothing is done while tracking is paused or the user is away, since the focused
+// window is not followed then.
+func (e *Enforcer) check(now time.Time) {
+	e.mu.Lock()
+	e.account(now)
+	rule := e.blockedBy(now)
+	if rule == nil || len(e.away) > 0 || now.Before(e.overrideUntil) || now.Sub(e.lastActed[rule.Name]) < e.rules.cooldown {
+		e.mu.Unlock()
+		return
+	}
+	e.lastActed[rule.Name] = now
+	reason := rule.Describe(now, e.used[rule.Name])
+	app, address, previous := e.focused.App, e.focusedAddress, e.previous
+	e.mu.Unlock()
+
+	// Windows are targeted by address, so a focus change meanwhile does not make the
+	// dispatch act on another window
+	var dispatch string
+	switch rule.Action {
+	case EnforceMove:
+		if address != "" {
+			dispatch = "movetoworkspacesilent " + rule.Workspace + ",address:" + address
+		}
+	case EnforceFocusPrevious:
+		if previous != "" {
+			dispatch = "focuswindow address:" + previous
+		}
+	}
+
+	log.Printf("Enforcing %s on %s: %s", rule.Name, app, reason)
+	if dispatch != "" {
+		if _, err := e.client.Dispatch(dispatch); err != nil {
+			log.Printf("Error dispatching %q: %v", dispatch, err)
+		}
+	}
+	e.notifier.Notify("enforce:"+rule.Name, "Blocked: "+app, reason+". Override with -override.")
+}
+
+// Run follows the activity stream until the context is canceled, checking the focused
+// window on every change and periodically for daily limits
+func (e *Enforcer) Run(ctx context.Context, wg *sync.WaitGroup, hub *ActivityHub, dbPath string) {
+	defer wg.Done()
+
+	entries := hub.Subscribe(100)
+	defer hub.Unsubscribe(entries)
+	e.seed(dbPath, time.Now())
+
+	ticker := time.NewTicker(enforcementCheckInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case entry := <-entries:
+			e.observe(entry, e.activeAddress(entry))
+			if entry.EventType == string(event.EventActiveWindow) {
+				e.check(time.Now())
+			}
+		case now := <-ticker.C:
+			e.check(now)
+		}
+	}
+}
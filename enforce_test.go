@@ -0,0 +1,227 @@
+package main
+
+import (
+	"encoding/json"
+	"net"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/thiagokokada/hyprland-go/event"
+)
+
+// fakeHyprland serves the Hyprland request socket, answering activewindow with the
+// window set by focus and recording the dispatches
+type fakeHyprland struct {
+	socket string
+
+	mu         sync.Mutex
+	class      string
+	address    string
+	dispatches []string
+}
+
+func newFakeHyprland(t *testing.T) *fakeHyprland {
+	t.Helper()
+	f := &fakeHyprland{socket: filepath.Join(t.TempDir(), ".socket.sock")}
+	listener, err := net.Listen("unix", f.socket)
+	if err != nil {
+		t.Fatalf("listen on %s: %v", f.socket, err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			f.serve(conn)
+		}
+	}()
+	return f
+}
+
+func (f *fakeHyprland) serve(conn net.Conn) {
+	defer conn.Close()
+	buf := make([]byte, 8192)
+	n, err := conn.Read(buf)
+	if err != nil {
+		return
+	}
+	request := string(buf[:n])
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	switch {
+	case request == "j/activewindow":
+		data, _ := json.Marshal(map[string]string{"class": f.class, "address": f.address})
+		conn.Write(data)
+	case strings.HasPrefix(request, "dispatch "):
+		f.dispatches = append(f.dispatches, strings.TrimPrefix(request, "dispatch "))
+		conn.Write([]byte("ok"))
+	default:
+		conn.Write([]byte("unknown request"))
+	}
+}
+
+func (f *fakeHyprland) focus(class, address string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.class, f.address = class, address
+}
+
+func (f *fakeHyprland) takeDispatches() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	dispatches := f.dispatches
+	f.dispatches = nil
+	return dispatches
+}
+
+func newTestEnforcer(t *testing.T, socket string, rules ...EnforcementRule) *Enforcer {
+	t.Helper()
+	config := &Config{Enforcement: &EnforcementConfig{Rules: rules, Cooldown: "1m", DispatchSocket: socket}}
+	if err := config.Enforcement.validate(); err != nil {
+		t.Fatalf("validate: %v", err)
+	}
+	e, err := NewEnforcer(config, nil)
+	if err != nil {
+		t.Fatalf("NewEnforcer: %v", err)
+	}
+	return e
+}
+
+// focusWindow passes a focus change through the enforcer like Run does
+func focusWindow(e *Enforcer, hypr *fakeHyprland, class, address string, at time.Time) {
+	hypr.focus(class, address)
+	entry := LogEntry{
+		Timestamp: at,
+		EventType: string(event.EventActiveWindow),
+		EventData: event.ActiveWindow{Name: class, Title: class},
+	}
+	e.observe(entry, e.activeAddress(entry))
+}
+
+func TestEnforcerFocusesPreviousWindowByAddress(t *testing.T) {
+	hypr := newFakeHyprland(t)
+	e := newTestEnforcer(t, hypr.socket,
+		EnforcementRule{Name: "video", Match: []string{"mpv"}, Action: EnforceFocusPrevious})
+
+	start := time.Now()
+	focusWindow(e, hypr, "kitty", "0x1000", start)
+	focusWindow(e, hypr, "kitty", "0x2000", start.Add(time.Second))
+	focusWindow(e, hypr, "mpv", "0x3000", start.Add(2*time.Second))
+	e.check(start.Add(3 * time.Second))
+
+	want := []string{"focuswindow address:0x2000"}
+	if got := hypr.takeDispatches(); !reflect.DeepEqual(got, want) {
+		t.Errorf("dispatches = %q, want %q", got, want)
+	}
+}
+
+func TestEnforcerCooldown(t *testing.T) {
+	hypr := newFakeHyprland(t)
+	e := newTestEnforcer(t, hypr.socket,
+		EnforcementRule{Name: "video", Match: []string{"mpv"}, Action: EnforceMove})
+
+	start := time.Now()
+	focusWindow(e, hypr, "mpv", "0x3000", start)
+	want := []string{"movetoworkspacesilent " + DefaultBlockedWorkspace + ",address:0x3000"}
+
+	e.check(start)
+	if got := hypr.takeDispatches(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("first check: dispatches = %q, want %q", got, want)
+	}
+	e.check(start.Add(30 * time.Second))
+	if got := hypr.takeDispatches(); len(got) != 0 {
+		t.Errorf("check within the cooldown: dispatches = %q, want none", got)
+	}
+	e.check(start.Add(time.Minute))
+	if got := hypr.takeDispatches(); !reflect.DeepEqual(got, want) {
+		t.Errorf("check after the cooldown: dispatches = %q, want %q", got, want)
+	}
+}
+
+func TestEnforcerMoveNeedsAddress(t *testing.T) {
+	hypr := newFakeHyprland(t)
+	e := newTestEnforcer(t, hypr.socket,
+		EnforcementRule{Name: "video", Match: []string{"mpv"}, Action: EnforceMove})
+
+	// Hyprland already reports another window, so the address is unknown
+	hypr.focus("kitty", "0x1000")
+	entry := LogEntry{Timestamp: time.Now(), EventType: string(event.EventActiveWindow),
+		EventData: event.ActiveWindow{Name: "mpv", Title: "mpv"}}
+	e.observe(entry, e.activeAddress(entry))
+	e.check(time.Now())
+	if got := hypr.takeDispatches(); len(got) != 0 {
+		t.Errorf("move without an address: dispatches = %q, want none", got)
+	}
+}
+
+func TestEnforcerWaitsWhileAway(t *testing.T) {
+	hypr := newFakeHyprland(t)
+	e := newTestEnforcer(t, hypr.socket,
+		EnforcementRule{Name: "video", Match: []string{"mpv"}, Action: EnforceMove})
+
+	start := time.Now()
+	focusWindow(e, hypr, "mpv", "0x3000", start)
+	for _, eventType := range []string{PauseStartEventType, "idle_start"} {
+		e.observe(LogEntry{Timestamp: start.Add(time.Second), EventType: eventType}, "")
+	}
+	e.check(start.Add(2 * time.Second))
+	e.observe(LogEntry{Timestamp: start.Add(3 * time.Second), EventType: PauseEndEventType}, "")
+	e.check(start.Add(4 * time.Second))
+	if got := hypr.takeDispatches(); len(got) != 0 {
+		t.Errorf("check while away: dispatches = %q, want none", got)
+	}
+
+	e.observe(LogEntry{Timestamp: start.Add(5 * time.Second), EventType: "idle_end"}, "")
+	e.check(start.Add(6 * time.Second))
+	if got := hypr.takeDispatches(); len(got) != 1 {
+		t.Errorf("check after coming back: dispatches = %q, want one", got)
+	}
+}
+
+func TestOverrideCommandSuspendsEnforcement(t *testing.T) {
+	hypr := newFakeHyprland(t)
+	e := newTestEnforcer(t, hypr.socket,
+		EnforcementRule{Name: "video", Match: []string{"mpv"}, Action: EnforceMove})
+	enforcer = e
+	defer func() { enforcer = nil }()
+
+	override := func(duration string) OverrideResult {
+		t.Helper()
+		args, _ := json.Marshal(OverrideArgs{Duration: duration})
+		result, err := dispatchCommand(Request{Command: "override", Args: args}, nil)
+		if err != nil {
+			t.Fatalf("override %q: %v", duration, err)
+		}
+		return result.(OverrideResult)
+	}
+
+	now := time.Now()
+	focusWindow(e, hypr, "mpv", "0x3000", now)
+	if result := override("10m"); result.Until == nil || result.Until.Before(now.Add(10*time.Minute)) {
+		t.Fatalf("override 10m: until = %v, want 10 minutes from now", result.Until)
+	}
+	e.check(now.Add(time.Second))
+	if got := hypr.takeDispatches(); len(got) != 0 {
+		t.Errorf("check during the override: dispatches = %q, want none", got)
+	}
+
+	if result := override("off"); result.Until != nil {
+		t.Fatalf("override off: until = %v, want none", result.Until)
+	}
+	e.check(now.Add(2 * time.Second))
+	if got := hypr.takeDispatches(); len(got) != 1 {
+		t.Errorf("check after lifting the override: dispatches = %q, want one", got)
+	}
+
+	if _, err := dispatchCommand(Request{Command: "override", Args: json.RawMessage(`{"duration":"soon"}`)}, nil); err == nil {
+		t.Error("override with an invalid duration succeeded")
+	}
+}
@@ -0,0 +1,283 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	DefaultDeepWorkMin             = 25 * time.Minute
+	DefaultDeepWorkInterruptionMax = 2 * time.Minute
+	DefaultDeepWorkInterruptions   = 2
+	transitionMatrixSize           = 8
+)
+
+// FocusSpan is an uninterrupted stretch of contiguous intervals with the same key.
+// Title changes within the same app do not end a span.
+type FocusSpan struct {
+	Key      string
+	App      string
+	Title    string
+	Category string
+	Start    time.Time
+	End      time.Time
+}
+
+func (s FocusSpan) Duration() time.Duration {
+	return s.End.Sub(s.Start)
+}
+
+// DeepWorkBlock is a long stretch of allowed activity with few short interruptions
+type DeepWorkBlock struct {
+	Start         time.Time
+	End           time.Time
+	Interruptions int
+}
+
+// DeepWorkRules configures what counts as deep work
+type DeepWorkRules struct {
+	MinDuration      time.Duration
+	Categories       []string
+	MaxInterruptions int
+	InterruptionMax  time.Duration
+}
+
+// FocusStats holds the focus and context-switch metrics for a set of intervals
+type FocusStats struct {
+	Active      time.Duration
+	Switches    int
+	Spans       []FocusSpan
+	DeepWork    []DeepWorkBlock
+	Transitions map[string]map[string]int
+}
+
+func (f *FocusStats) SwitchesPerHour() float64 {
+	if f.Active <= 0 {
+		return 0
+	}
+	return float64(f.Switches) / f.Active.Hours()
+}
+
+// BuildFocusSpans merges contiguous intervals sharing the same key into spans
+func BuildFocusSpans(intervals []Interval, keyFn func(Interval) string) []FocusSpan {
+	var spans []FocusSpan
+	for _, iv := range intervals {
+		key := keyFn(iv)
+		if len(spans) > 0 {
+			last := &spans[len(spans)-1]
+			if last.Key == key && !iv.Start.After(last.End) {
+				if iv.End.After(last.End) {
+					last.End = iv.End
+				}
+				continue
+			}
+		}
+		spans = append(spans, FocusSpan{Key: key, App: iv.App, Title: iv.Title, Category: iv.Category, Start: iv.Start, End: iv.End})
+	}
+	return spans
+}
+
+// CalculateFocusStats walks the spans in order, counting switches between contiguous
+// spans, the transition matrix between keys and the deep work blocks
+func CalculateFocusStats(intervals []Interval, keyFn func(Interval) string, config *Config, rules DeepWorkRules) *FocusStats {
+	stats := &FocusStats{
+		Spans:       BuildFocusSpans(intervals, keyFn),
+		Transitions: make(map[string]map[string]int),
+	}
+
+	for i, span := range stats.Spans {
+		stats.Active += span.Duration()
+		if i == 0 {
+			continue
+		}
+		prev := stats.Spans[i-1]
+		if span.Start.After(prev.End) {
+			// Separated by an idle period, not a switch
+			continue
+		}
+		stats.Switches++
+		if stats.Transitions[prev.Key] == nil {
+			stats.Transitions[prev.Key] = make(map[string]int)
+		}
+		stats.Transitions[prev.Key][span.Key]++
+	}
+
+	stats.DeepWork = FindDeepWork(stats.Spans, config, rules)
+	return stats
+}
+
+// FindDeepWork finds blocks of at least rules.MinDuration spent in the allowed categories.
+// Spans outside the allowed categories and idle gaps no longer than rules.InterruptionMax
+// count as interruptions; longer ones, or more than rules.MaxInterruptions, end the block.
+func FindDeepWork(spans []FocusSpan, config *Config, rules DeepWorkRules) []DeepWorkBlock {
+	allowed := func(span FocusSpan) bool {
+		if len(rules.Categories) == 0 {
+			return true
+		}
+		category := config.CategorizeInterval(Interval{App: span.App, Title: span.Title, Category: span.Category})
+		for _, c := range rules.Categories {
+			if strings.EqualFold(c, category) {
+				return true
+			}
+		}
+		return false
+	}
+
+	var blocks []DeepWorkBlock
+	var current *DeepWorkBlock
+	// End of the last span seen while a block is open, including interruptions
+	var lastEnd time.Time
+
+	finish := func() {
+		if current != nil && current.End.Sub(current.Start) >= rules.MinDuration {
+			blocks = append(blocks, *current)
+		}
+		current = nil
+	}
+
+	for _, span := range spans {
+		if current != nil {
+			if gap := span.Start.Sub(lastEnd); gap > 0 {
+				if gap > rules.InterruptionMax || current.Interruptions >= rules.MaxInterruptions {
+					finish()
+				} else {
+					current.Interruptions++
+				}
+			}
+		}
+
+		if allowed(span) {
+			if current == nil {
+				current = &DeepWorkBlock{Start: span.Start}
+			}
+			current.End = span.End
+			lastEnd = span.End
+			continue
+		}
+
+		if current == nil {
+			continue
+		}
+		if span.Duration() > rules.InterruptionMax || current.Interruptions >= rules.MaxInterruptions {
+			finish()
+			continue
+		}
+		// A short interruption: the block continues but its end stays at the last allowed span
+		current.Interruptions++
+		lastEnd = span.End
+	}
+	finish()
+
+	return blocks
+}
+
+func generateFocusReport(db *Database, startTime, endTime time.Time, config AnalysisConfig) {
+	intervals := loadIntervals(db, startTime, endTime, config)
+	stats := CalculateFocusStats(intervals, config.Config.GroupKeyFunc(config.GroupBy), config.Config, config.DeepWork)
+	header := groupByHeaders[config.GroupBy]
+
+	var deepWorkTime time.Duration
+	for _, block := range stats.DeepWork {
+		deepWorkTime += block.End.Sub(block.Start)
+	}
+
+	overview := &ReportTable{Title: "Focus Overview", Columns: []string{"Metric", "Value"}}
+	overview.AddRow("Active time", stats.Active)
+	overview.AddRow("Switches", stats.Switches)
+	overview.AddRow("Switches per hour", stats.SwitchesPerHour())
+	overview.AddRow("Deep work blocks", len(stats.DeepWork))
+	overview.AddRow("Deep work time", deepWorkTime)
+
+	spansTable := &ReportTable{
+		Title:   fmt.Sprintf("Uninterrupted Spans Per %s", header),
+		Columns: []string{header, "Spans", "Median", "Longest", "Total"},
+	}
+	spansByKey := make(map[string][]time.Duration)
+	for _, span := range stats.Spans {
+		spansByKey[span.Key] = append(spansByKey[span.Key], span.Duration())
+	}
+	totals := make(map[string]time.Duration)
+	for key, durations := range spansByKey {
+		for _, d := range durations {
+			totals[key] += d
+		}
+	}
+	for _, item := range SortedSummary(totals, config.TotalMinDuration()) {
+		durations := spansByKey[item.Name]
+		sort.Slice(durations, func(i, j int) bool { return durations[i] < durations[j] })
+		spansTable.AddRow(item.Name, len(durations), Percentile(durations, 50), durations[len(durations)-1], item.Duration)
+	}
+
+	deepWorkTable := &ReportTable{
+		Title: fmt.Sprintf("Deep Work Blocks (at least %s, at most %d interruptions of up to %s)",
+			FormatDuration(config.DeepWork.MinDuration), config.DeepWork.MaxInterruptions, FormatDuration(config.DeepWork.InterruptionMax)),
+		Columns: []string{"Date", "Start", "End", "Duration", "Interruptions"},
+	}
+	for _, block := range stats.DeepWork {
+		day := BucketStart(block.Start, BucketDay)
+		deepWorkTable.AddRow(day.Format("Mon 2006-01-02"), clockTime(block.Start, day), clockTime(block.End, day), block.End.Sub(block.Start), block.Interruptions)
+	}
+
+	tables := []*ReportTable{overview, spansTable, deepWorkTable, TransitionMatrixTable(stats.Transitions, header)}
+	if err := RenderTables(os.Stdout, config.Format, tables...); err != nil {
+		log.Fatalf("Error writing report: %v", err)
+	}
+}
+
+// TransitionMatrixTable shows how often focus moved from each row key to each column key,
+// limited to the keys involved in the most switches
+func TransitionMatrixTable(transitions map[string]map[string]int, header string) *ReportTable {
+	involvement := make(map[string]int)
+	for from, targets := range transitions {
+		for to, count := range targets {
+			involvement[from] += count
+			involvement[to] += count
+		}
+	}
+	keys := make([]string, 0, len(involvement))
+	for key := range involvement {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if involvement[keys[i]] != involvement[keys[j]] {
+			return involvement[keys[i]] > involvement[keys[j]]
+		}
+		return keys[i] < keys[j]
+	})
+	if len(keys) > transitionMatrixSize {
+		keys = keys[:transitionMatrixSize]
+	}
+
+	table := &ReportTable{
+		Title:   fmt.Sprintf("Transitions (row %s switched to column)", strings.ToLower(header)),
+		Columns: append([]string{"From \\ To"}, keys...),
+	}
+	for _, from := range keys {
+		row := []any{from}
+		for _, to := range keys {
+			row = append(row, transitions[from][to])
+		}
+		table.AddRow(row...)
+	}
+	return table
+}
+
+// Percentile returns the p-th percentile of sorted durations using the nearest-rank method
+func Percentile(sorted []time.Duration, p float64) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	rank := int(math.Ceil(p / 100 * float64(len(sorted))))
+	if rank < 1 {
+		rank = 1
+	}
+	if rank > len(sorted) {
+		rank = len(sorted)
+	}
+	return sorted[rank-1]
+}
@@ -4,11 +4,9 @@ go 1.24.2
 
 require (
 	fyne.io/systray v1.11.0
+	github.com/godbus/dbus/v5 v5.1.0
+	github.com/mattn/go-sqlite3 v1.14.28
 	github.com/thiagokokada/hyprland-go v0.4.1
 )
 
-require (
-	github.com/godbus/dbus/v5 v5.1.0 // indirect
-	github.com/mattn/go-sqlite3 v1.14.28 // indirect
-	golang.org/x/sys v0.15.0 // indirect
-)
+require golang.org/x/sys v0.15.0 // indirect
@@ -0,0 +1,338 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	DefaultGoalWarnAt   = 0.8
+	goalMonitorInterval = time.Minute
+)
+
+// Goal is a daily or weekly target ("at least 4h in code on weekdays") or limit
+// ("at most 45m on social per day") for the time matching its patterns. Patterns
+// are matched like project patterns, e.g. "category:social" or "title:*YouTube*".
+type Goal struct {
+	Name  string   `json:"name"`
+	Match []string `json:"match"`
+	// Exactly one of Min (a target) and Max (a limit) is set, e.g. "4h" or "45m"
+	Min string `json:"min"`
+	Max string `json:"max"`
+	// Period is "day" (default) or "week"
+	Period string `json:"period"`
+	// Days limits daily goals to some weekdays, e.g. ["weekdays"] or ["sat", "sun"]
+	Days []string `json:"days"`
+	// WarnAt is the fraction of a limit at which a warning is sent (default 0.8)
+	WarnAt float64 `json:"warn_at"`
+
+	target time.Duration
+	unit   BucketUnit
+	days   map[time.Weekday]bool
+}
+
+// validate parses the durations and weekdays of the goal and fills in the defaults
+func (g *Goal) validate() error {
+	if g.Name == "" {
+		return fmt.Errorf("goal without a name")
+	}
+	if len(g.Match) == 0 {
+		return fmt.Errorf("goal %s has no match patterns", g.Name)
+	}
+	if (g.Min == "") == (g.Max == "") {
+		return fmt.Errorf("goal %s needs exactly one of min and max", g.Name)
+	}
+	target, err := time.ParseDuration(g.Min + g.Max)
+	if err != nil || target <= 0 {
+		return fmt.Errorf("invalid duration %q in goal %s", g.Min+g.Max, g.Name)
+	}
+	g.target = target
+
+	switch g.Period {
+	case "", "day":
+		g.Period, g.unit = "day", BucketDay
+	case "week":
+		g.unit = BucketWeek
+	default:
+		return fmt.Errorf("invalid period %q in goal %s (must be 'day' or 'week')", g.Period, g.Name)
+	}
+
+	if g.days, err = parseDays(g.Days); err != nil {
+		return fmt.Errorf("%v in goal %s", err, g.Name)
+	}
+
+	if g.WarnAt == 0 {
+		g.WarnAt = DefaultGoalWarnAt
+	}
+	return nil
+}
+
+// parseDays parses weekday names, "weekdays" and "weekends" into a set of weekdays,
+// nil if days is empty
+func parseDays(days []string) (map[time.Weekday]bool, error) {
+	if len(days) == 0 {
+		return nil, nil
+	}
+	set := make(map[time.Weekday]bool)
+	for _, day := range days {
+		switch day = strings.ToLower(day); day {
+		case "weekdays":
+			for d := time.Monday; d <= time.Friday; d++ {
+				set[d] = true
+			}
+		case "weekends":
+			set[time.Saturday], set[time.Sunday] = true, true
+		default:
+			weekday, ok := parseWeekday(day)
+			if !ok {
+				return nil, fmt.Errorf("invalid day %q", day)
+			}
+			set[weekday] = true
+		}
+	}
+	return set, nil
+}
+
+// IsLimit reports whether the goal is an upper bound rather than a target
+func (g *Goal) IsLimit() bool {
+	return g.Max != ""
+}
+
+// AppliesOn reports whether the goal is evaluated for the period containing t
+func (g *Goal) AppliesOn(t time.Time) bool {
+	return g.unit != BucketDay || g.days == nil || g.days[t.Local().Weekday()]
+}
+
+// Describe returns the goal in words, e.g. "at most 45m 0s per day"
+func (g *Goal) Describe() string {
+	bound := "at least"
+	if g.IsLimit() {
+		bound = "at most"
+	}
+	return fmt.Sprintf("%s %s per %s", bound, FormatDuration(g.target), g.Period)
+}
+
+func (g *Goal) matches(iv Interval, config *Config) bool {
+	for _, pattern := range g.Match {
+		if config.MatchIntervalPattern(pattern, iv) {
+			return true
+		}
+	}
+	return false
+}
+
+// GoalProgress is the time spent towards a goal in one day or week
+type GoalProgress struct {
+	Goal   *Goal
+	Period time.Time
+	Spent  time.Duration
+}
+
+func (p GoalProgress) Percent() float64 {
+	return float64(p.Spent) / float64(p.Goal.target) * 100
+}
+
+// Status describes the progress: limits are "ok", "approaching" or "exceeded",
+// targets are "met", or "in progress" until the period is over and "missed" after
+func (p GoalProgress) Status(now time.Time) string {
+	if p.Goal.IsLimit() {
+		switch {
+		case p.Spent > p.Goal.target:
+			return "exceeded"
+		case float64(p.Spent) >= p.Goal.WarnAt*float64(p.Goal.target):
+			return "approaching"
+		default:
+			return "ok"
+		}
+	}
+	switch {
+	case p.Spent >= p.Goal.target:
+		return "met"
+	case now.Before(NextBucket(p.Period, p.Goal.unit)):
+		return "in progress"
+	default:
+		return "missed"
+	}
+}
+
+// Label is the short progress shown in the tray menu
+func (p GoalProgress) Label() string {
+	return fmt.Sprintf("%s: %s / %s (%.0f%%)", p.Goal.Name, FormatDuration(p.Spent), FormatDuration(p.Goal.target), p.Percent())
+}
+
+// EvaluateGoals sums the time matching each goal in every period within [from, to)
+// the goal applies to, oldest first
+func EvaluateGoals(goals []Goal, intervals []Interval, config *Config, from, to time.Time) []GoalProgress {
+	var progress []GoalProgress
+	for i := range goals {
+		goal := &goals[i]
+		spent := make(map[time.Time]time.Duration)
+		for _, piece := range SplitIntervalsByBucket(intervals, goal.unit) {
+			if goal.matches(piece.Interval, config) {
+				spent[piece.Bucket] += piece.Duration()
+			}
+		}
+		for _, period := range BucketRange(from, to.Add(-time.Nanosecond), goal.unit) {
+			if goal.AppliesOn(period) {
+				progress = append(progress, GoalProgress{Goal: goal, Period: period, Spent: spent[period]})
+			}
+		}
+	}
+	return progress
+}
+
+// currentGoalRange returns the range covering the current period of every goal
+func currentGoalRange(goals []Goal, now time.Time) time.Time {
+	start := BucketStart(now, BucketDay)
+	for _, g := range goals {
+		if g.unit == BucketWeek {
+			start = minTime(start, BucketStart(now, BucketWeek))
+		}
+	}
+	return start
+}
+
+// RunGoalMonitor periodically evaluates the goals against the current day and week,
+// updating the tray menu and notifying once per period when a limit is approached or
+// exceeded, or a target is met
+func RunGoalMonitor(ctx context.Context, wg *sync.WaitGroup, dbPath string, config *Config, notifier *Notifier) {
+	defer wg.Done()
+
+	db, err := OpenDatabase(dbPath)
+	if err != nil {
+		log.Printf("Goal monitor disabled, failed to open database: %v", err)
+		return
+	}
+	defer db.Close()
+
+	// Highest status already notified per goal and period
+	notified := make(map[string]string)
+
+	evaluate := func() {
+		now := time.Now()
+		var current []GoalProgress
+		start := currentGoalRange(config.Goals, now)
+		intervals, err := db.GetIntervals(start, now, now)
+		if err != nil {
+			log.Printf("Error evaluating goals: %v", err)
+			return
+		}
+		for _, p := range EvaluateGoals(config.Goals, intervals, config, start, now) {
+			if p.Period.Equal(BucketStart(now, p.Goal.unit)) {
+				current = append(current, p)
+			}
+		}
+		updateGoalMenu(current)
+
+		for _, p := range current {
+			status := p.Status(now)
+			key := p.Goal.Name + "|" + p.Period.Format(time.RFC3339)
+			if status == notified[key] || (status == "approaching" && notified[key] == "exceeded") {
+				continue
+			}
+
+			var summary string
+			switch status {
+			case "approaching":
+				summary = "Approaching limit: " + p.Goal.Name
+			case "exceeded":
+				summary = "Limit exceeded: " + p.Goal.Name
+			case "met":
+				summary = "Goal reached: " + p.Goal.Name
+			default:
+				continue
+			}
+			notified[key] = status
+			body := fmt.Sprintf("%s of %s this %s", FormatDuration(p.Spent), FormatDuration(p.Goal.target), p.Goal.Period)
+			log.Printf("%s (%s)", summary, body)
+			notifier.Notify("goal:"+p.Goal.Name, summary, body)
+			eventStream.Publish(StreamEvent{Event: StreamGoal, Time: now, Goal: &GoalAlert{
+				Name:          p.Goal.Name,
+				Status:        status,
+				Period:        BucketLabel(p.Period, p.Goal.unit),
+				SpentSeconds:  int64(p.Spent.Seconds()),
+				TargetSeconds: int64(p.Goal.target.Seconds()),
+			}})
+		}
+	}
+
+	ticker := time.NewTicker(goalMonitorInterval)
+	defer ticker.Stop()
+	evaluate()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			evaluate()
+		}
+	}
+}
+
+func generateGoalsReport(db *Database, startTime, endTime time.Time, config AnalysisConfig) {
+	goals := config.Config.Goals
+	if len(goals) == 0 {
+		log.Fatalf("Error: the goals report needs a \"goals\" section in the configuration file")
+	}
+
+	now := time.Now()
+	// The current periods are always shown, even when they began before the range
+	currentStart := currentGoalRange(goals, now)
+	if !startTime.IsZero() && currentStart.Before(startTime) {
+		startTime = currentStart
+	}
+	intervals, err := db.GetIntervals(startTime, endTime, now)
+	if err != nil {
+		log.Fatalf("Error retrieving intervals from database: %v", err)
+	}
+	intervals = config.FilterIntervals(intervals)
+	// With -time-range all, the history starts at the first recorded activity
+	if startTime.IsZero() {
+		startTime = currentStart
+		if len(intervals) > 0 && intervals[0].Start.Before(startTime) {
+			startTime = intervals[0].Start
+		}
+	}
+
+	currentTable := &ReportTable{Title: "Current Goals", Columns: []string{"Goal", "Rule", "Spent", "Progress %", "Status"}}
+	historyTable := &ReportTable{Title: "Goal History", Columns: []string{"Period", "Goal", "Spent", "Progress %", "Status"}}
+	summaryTable := &ReportTable{Title: "Goal Summary", Columns: []string{"Goal", "Rule", "Periods", "Met", "Met %"}}
+
+	type summary struct{ periods, met int }
+	summaries := make(map[string]*summary)
+	for _, p := range EvaluateGoals(goals, intervals, config.Config, startTime, endTime) {
+		status := p.Status(now)
+		if p.Period.Equal(BucketStart(now, p.Goal.unit)) {
+			currentTable.AddRow(p.Goal.Name, p.Goal.Describe(), p.Spent, p.Percent(), status)
+		}
+		historyTable.AddRow(BucketLabel(p.Period, p.Goal.unit), p.Goal.Name, p.Spent, p.Percent(), status)
+
+		// Periods still in progress are left out of the summary
+		if status == "in progress" || !now.After(NextBucket(p.Period, p.Goal.unit)) {
+			continue
+		}
+		s, ok := summaries[p.Goal.Name]
+		if !ok {
+			s = &summary{}
+			summaries[p.Goal.Name] = s
+		}
+		s.periods++
+		if status == "met" || status == "ok" || status == "approaching" {
+			s.met++
+		}
+	}
+	for i := range goals {
+		if s, ok := summaries[goals[i].Name]; ok {
+			summaryTable.AddRow(goals[i].Name, goals[i].Describe(), s.periods, s.met, float64(s.met)/float64(s.periods)*100)
+		}
+	}
+
+	if err := RenderTables(os.Stdout, config.Format, currentTable, historyTable, summaryTable); err != nil {
+		log.Fatalf("Error writing report: %v", err)
+	}
+}
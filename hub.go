@@ -0,0 +1,80 @@
+package main
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// ActivityHub sits between the event producers (Hyprland handler, socket) and the
+// database logger, and passes every log entry on to the observers that want to react
+// to activity as it happens. Observers never slow down logging: entries are dropped
+// for an observer whose buffer is full.
+type ActivityHub struct {
+	mu        sync.Mutex
+	observers map[chan LogEntry]struct{}
+	pings     chan struct{}
+}
+
+func NewActivityHub() *ActivityHub {
+	return &ActivityHub{observers: make(map[chan LogEntry]struct{}), pings: make(chan struct{})}
+}
+
+// Subscribe returns a channel receiving the log entries, buffering up to size entries
+func (h *ActivityHub) Subscribe(size int) chan LogEntry {
+	ch := make(chan LogEntry, size)
+	h.mu.Lock()
+	h.observers[ch] = struct{}{}
+	h.mu.Unlock()
+	return ch
+}
+
+func (h *ActivityHub) Unsubscribe(ch chan LogEntry) {
+	h.mu.Lock()
+	delete(h.observers, ch)
+	h.mu.Unlock()
+}
+
+func (h *ActivityHub) publish(entry LogEntry) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for ch := range h.observers {
+		select {
+		case ch <- entry:
+		default:
+		}
+	}
+}
+
+// Run forwards the entries from in to the database logger on out and publishes them
+// to the observers, until in is closed or the context is canceled
+func (h *ActivityHub) Run(ctx context.Context, in <-chan LogEntry, out chan<- LogEntry) {
+	defer close(out)
+	for {
+		select {
+		case entry, ok := <-in:
+			if !ok {
+				return
+			}
+			select {
+			case out <- entry:
+			case <-ctx.Done():
+			}
+			h.publish(entry)
+		case <-h.pings:
+		}
+	}
+}
+
+// Ping reports whether Run is ready for the next entry within timeout, which it is not
+// while the database logger falls behind
+func (h *ActivityHub) Ping(timeout time.Duration) bool {
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case h.pings <- struct{}{}:
+		return true
+	case <-timer.C:
+		return false
+	}
+}
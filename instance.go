@@ -0,0 +1,154 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+const (
+	// takeoverTimeout is how long -replace waits for the running daemon to shut down
+	takeoverTimeout  = 15 * time.Second
+	takeoverInterval = 100 * time.Millisecond
+)
+
+var errInstanceLocked = errors.New("instance lock is held")
+
+// InstanceLock keeps a second daemon from writing to the same database. It is an
+// exclusive flock on a pidfile next to the database, which the kernel releases when
+// the daemon exits, however it exits.
+type InstanceLock struct {
+	file *os.File
+}
+
+// PidFilePath returns the pidfile locked by the daemon using the database
+func PidFilePath(dbPath string) string {
+	return dbPath + ".pid"
+}
+
+// lockInstance takes the instance lock without waiting. If another process holds it,
+// errInstanceLocked is returned with the pid written by that process.
+func lockInstance(dbPath string) (*InstanceLock, int, error) {
+	file, err := os.OpenFile(PidFilePath(dbPath), os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to open pidfile: %v", err)
+	}
+	if err := syscall.Flock(int(file.Fd()), syscall.LOCK_EX|syscall.LOCK_NB); err != nil {
+		defer file.Close()
+		if errors.Is(err, syscall.EWOULDBLOCK) {
+			return nil, readPid(file), errInstanceLocked
+		}
+		return nil, 0, fmt.Errorf("failed to lock pidfile: %v", err)
+	}
+
+	if err := file.Truncate(0); err == nil {
+		_, err = file.WriteAt([]byte(strconv.Itoa(os.Getpid())+"\n"), 0)
+	}
+	if err != nil {
+		file.Close()
+		return nil, 0, fmt.Errorf("failed to write pidfile: %v", err)
+	}
+	return &InstanceLock{file: file}, 0, nil
+}
+
+func readPid(file *os.File) int {
+	data := make([]byte, 32)
+	n, _ := file.ReadAt(data, 0)
+	pid, _ := strconv.Atoi(strings.TrimSpace(string(data[:n])))
+	return pid
+}
+
+// Release clears the pidfile and gives up the lock. The file itself stays, since a
+// daemon starting at the same moment may already have it open and would otherwise
+// lock a file nobody else can see.
+func (l *InstanceLock) Release() {
+	if l == nil {
+		return
+	}
+	if err := l.file.Truncate(0); err != nil {
+		log.Printf("Error clearing pidfile: %v", err)
+	}
+	l.file.Close()
+}
+
+// probeDaemon returns the status of the daemon answering on the control socket, or
+// nil if none does. A socket left behind by a daemon that crashed does not answer.
+func probeDaemon() *StatusResult {
+	client, err := DialControl(SocketPath)
+	if err != nil {
+		return nil
+	}
+	defer client.Close()
+	var status StatusResult
+	if err := client.Call("status", nil, &status); err != nil {
+		// A daemon too old to know the command is still running
+		log.Printf("Warning: the daemon on %s did not report its status: %v", SocketPath, err)
+	}
+	return &status
+}
+
+// ClaimInstance makes sure no other daemon uses the database or the control socket.
+// With replace, a running daemon is asked to shut down over the socket and the new
+// one waits for it to finish; otherwise starting fails. The socket passed to a
+// socket-activated daemon is its own and is not probed.
+func ClaimInstance(dbPath string, replace bool, activated bool) (*InstanceLock, error) {
+	lock, holder, err := lockInstance(dbPath)
+	if err != nil && err != errInstanceLocked {
+		return nil, err
+	}
+	// A daemon using another database would still lose its socket to this one
+	var running *StatusResult
+	if !activated {
+		running = probeDaemon()
+	}
+	if running == nil {
+		if lock == nil {
+			return nil, fmt.Errorf("another hyprtracker daemon (pid %d) is using %s and does not answer on %s; stop it first",
+				holder, dbPath, SocketPath)
+		}
+		return lock, nil
+	}
+
+	if !replace {
+		lock.Release()
+		return nil, fmt.Errorf("hyprtracker is already running (pid %d, database %s) and listening on %s; stop it first or start with -replace",
+			running.Pid, running.DBPath, SocketPath)
+	}
+
+	log.Printf("Asking the running daemon (pid %d) to shut down...", running.Pid)
+	if err := requestShutdown(); err != nil {
+		lock.Release()
+		return nil, fmt.Errorf("failed to ask the running daemon to shut down: %v", err)
+	}
+	deadline := time.Now().Add(takeoverTimeout)
+	for {
+		if lock == nil {
+			if lock, _, err = lockInstance(dbPath); err != nil && err != errInstanceLocked {
+				return nil, err
+			}
+		}
+		if lock != nil && probeDaemon() == nil {
+			log.Printf("Took over from the daemon with pid %d", running.Pid)
+			return lock, nil
+		}
+		if time.Now().After(deadline) {
+			lock.Release()
+			return nil, fmt.Errorf("the running daemon (pid %d) did not shut down within %s", running.Pid, FormatDuration(takeoverTimeout))
+		}
+		time.Sleep(takeoverInterval)
+	}
+}
+
+func requestShutdown() error {
+	client, err := DialControl(SocketPath)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+	return client.Call("shutdown", nil, nil)
+}
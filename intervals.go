@@ -0,0 +1,292 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/thiagokokada/hyprland-go/event"
+)
+
+// Interval is a span of time during which a single window had focus, or a manual entry
+type Interval struct {
+	Start time.Time
+	End   time.Time
+	App   string
+	Title string
+	// Category overrides the configured category rules, set for manual entries
+	Category string
+	ManualID int64
+	// Tag is the label set with -tag while the interval was recorded
+	Tag string
+}
+
+func (iv Interval) Duration() time.Duration {
+	return iv.End.Sub(iv.Start)
+}
+
+// WindowKey returns the "App - Title" key used by the per-window reports
+func (iv Interval) WindowKey() string {
+	return fmt.Sprintf("%s - %s", iv.App, iv.Title)
+}
+
+// MatchesKeywords reports whether the interval's app or title contains any of the
+// given (already lowercased) keywords. An empty keyword list matches everything.
+func (iv Interval) MatchesKeywords(keywords []string) bool {
+	if len(keywords) == 0 {
+		return true
+	}
+	searchText := strings.ToLower(iv.App + " " + iv.Title)
+	for _, keyword := range keywords {
+		if strings.Contains(searchText, keyword) {
+			return true
+		}
+	}
+	return false
+}
+
+// awayEventKind returns the away kind ("idle", "lock", "suspend" or "pause") of a marker
+// event and whether it starts or ends the period
+func awayEventKind(eventType string) (kind string, start bool, ok bool) {
+	for _, k := range []string{"idle", "lock", "suspend", "pause"} {
+		switch eventType {
+		case k + "_start":
+			return k, true, true
+		case k + "_end":
+			return k, false, true
+		}
+	}
+	return "", false, false
+}
+
+// BuildIntervals turns the raw event stream into focus intervals. Each active window
+// event opens an interval that is closed by the next event; nothing is counted while
+// an idle, lock or suspend period is open. If openEnd is non-zero, the trailing
+// interval is closed at openEnd, otherwise it is dropped since its end is unknown.
+func BuildIntervals(entries []LogEntry, openEnd time.Time) []Interval {
+	var intervals []Interval
+	var current *LogEntry
+	var tag string
+	away := make(map[string]bool)
+
+	closeCurrent := func(end time.Time) {
+		if current == nil || len(away) > 0 {
+			return
+		}
+		if !end.After(current.Timestamp) {
+			return
+		}
+		intervals = append(intervals, Interval{
+			Start: current.Timestamp,
+			End:   end,
+			App:   current.EventData.Name,
+			Title: current.EventData.Title,
+			Tag:   tag,
+		})
+	}
+
+	for i := range entries {
+		entry := entries[i]
+		// Break reminders and focus sessions don't interrupt the focused window
+		if isBreakEvent(entry.EventType) || isFocusSessionEvent(entry.EventType) {
+			continue
+		}
+		closeCurrent(entry.Timestamp)
+
+		if kind, start, ok := awayEventKind(entry.EventType); ok {
+			if start {
+				away[kind] = true
+			} else {
+				delete(away, kind)
+			}
+			current = nil
+			continue
+		}
+
+		switch entry.EventType {
+		case string(event.EventActiveWindow):
+			current = &entries[i]
+		default:
+			if entry.EventType == TagEventType {
+				tag = entry.EventData.Title
+			}
+			// Other event types only split the current interval
+			if current != nil {
+				resumed := *current
+				resumed.Timestamp = entry.Timestamp
+				current = &resumed
+			}
+		}
+	}
+
+	if !openEnd.IsZero() {
+		closeCurrent(openEnd)
+	}
+
+	return intervals
+}
+
+// AwayPeriod is a span during which the user was idle, the screen was locked or the
+// machine was suspended
+type AwayPeriod struct {
+	Start time.Time
+	End   time.Time
+	Kind  string
+	// Reason is why tracking was paused, for pauses
+	Reason string
+}
+
+// BuildAwayPeriods pairs the idle, lock and suspend markers in the event stream.
+// Periods still open at the end are closed at openEnd, or dropped if it is zero.
+func BuildAwayPeriods(entries []LogEntry, openEnd time.Time) []AwayPeriod {
+	var periods []AwayPeriod
+	open := make(map[string]LogEntry)
+
+	for _, entry := range entries {
+		kind, start, ok := awayEventKind(entry.EventType)
+		if !ok {
+			continue
+		}
+		if start {
+			if _, exists := open[kind]; !exists {
+				open[kind] = entry
+			}
+			continue
+		}
+		if started, exists := open[kind]; exists {
+			periods = append(periods, AwayPeriod{Start: started.Timestamp, End: entry.Timestamp, Kind: kind, Reason: started.EventData.Title})
+			delete(open, kind)
+		}
+	}
+
+	if !openEnd.IsZero() {
+		for kind, started := range open {
+			periods = append(periods, AwayPeriod{Start: started.Timestamp, End: openEnd, Kind: kind, Reason: started.EventData.Title})
+		}
+	}
+
+	sort.Slice(periods, func(i, j int) bool {
+		return periods[i].Start.Before(periods[j].Start)
+	})
+
+	return periods
+}
+
+// FilterIntervals keeps only the intervals matching the given keywords
+func FilterIntervals(intervals []Interval, keywords []string) []Interval {
+	if len(keywords) == 0 {
+		return intervals
+	}
+	filtered := make([]Interval, 0, len(intervals))
+	for _, iv := range intervals {
+		if iv.MatchesKeywords(keywords) {
+			filtered = append(filtered, iv)
+		}
+	}
+	return filtered
+}
+
+// BucketUnit is the granularity used when splitting intervals into time buckets
+type BucketUnit string
+
+const (
+	BucketHour  BucketUnit = "hour"
+	BucketDay   BucketUnit = "day"
+	BucketWeek  BucketUnit = "week"
+	BucketMonth BucketUnit = "month"
+)
+
+func ParseBucketUnit(s string) (BucketUnit, error) {
+	switch unit := BucketUnit(strings.ToLower(s)); unit {
+	case BucketHour, BucketDay, BucketWeek, BucketMonth:
+		return unit, nil
+	default:
+		return "", fmt.Errorf("invalid bucket %q (must be 'hour', 'day', 'week' or 'month')", s)
+	}
+}
+
+// BucketStart returns the start of the local-time bucket containing t. Weeks start on Monday.
+func BucketStart(t time.Time, unit BucketUnit) time.Time {
+	t = t.Local()
+	switch unit {
+	case BucketHour:
+		return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), 0, 0, 0, time.Local)
+	case BucketWeek:
+		day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.Local)
+		offset := (int(day.Weekday()) + 6) % 7
+		return day.AddDate(0, 0, -offset)
+	case BucketMonth:
+		return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.Local)
+	default:
+		return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.Local)
+	}
+}
+
+// NextBucket returns the start of the bucket following the one starting at start.
+// Calendar arithmetic is used so that DST transitions produce 23h/25h days.
+func NextBucket(start time.Time, unit BucketUnit) time.Time {
+	switch unit {
+	case BucketHour:
+		return time.Date(start.Year(), start.Month(), start.Day(), start.Hour()+1, 0, 0, 0, time.Local)
+	case BucketWeek:
+		return start.AddDate(0, 0, 7)
+	case BucketMonth:
+		return start.AddDate(0, 1, 0)
+	default:
+		return start.AddDate(0, 0, 1)
+	}
+}
+
+// BucketLabel formats a bucket start for use as a column header
+func BucketLabel(start time.Time, unit BucketUnit) string {
+	switch unit {
+	case BucketHour:
+		return start.Format("01-02 15h")
+	case BucketWeek:
+		year, week := start.ISOWeek()
+		return fmt.Sprintf("%d-W%02d", year, week)
+	case BucketMonth:
+		return start.Format("2006-01")
+	default:
+		return start.Format("Mon 01-02")
+	}
+}
+
+// BucketedInterval is the part of an interval that falls inside a single bucket
+type BucketedInterval struct {
+	Interval
+	Bucket time.Time
+}
+
+// SplitIntervalsByBucket splits every interval at bucket boundaries so that each
+// resulting piece lies entirely within one bucket
+func SplitIntervalsByBucket(intervals []Interval, unit BucketUnit) []BucketedInterval {
+	var pieces []BucketedInterval
+	for _, iv := range intervals {
+		start := iv.Start
+		for start.Before(iv.End) {
+			bucket := BucketStart(start, unit)
+			end := NextBucket(bucket, unit)
+			if end.After(iv.End) {
+				end = iv.End
+			}
+			piece := iv
+			piece.Start = start
+			piece.End = end
+			pieces = append(pieces, BucketedInterval{Interval: piece, Bucket: bucket})
+			start = end
+		}
+	}
+	return pieces
+}
+
+// BucketRange lists every bucket start between from and to (inclusive of the bucket containing to)
+func BucketRange(from, to time.Time, unit BucketUnit) []time.Time {
+	var buckets []time.Time
+	last := BucketStart(to, unit)
+	for b := BucketStart(from, unit); !b.After(last); b = NextBucket(b, unit) {
+		buckets = append(buckets, b)
+	}
+	return buckets
+}
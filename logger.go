@@ -17,6 +17,8 @@ type DebouncedActivityLogger struct {
 	lastActivityTime     time.Time
 	isIdle               bool
 	config               LoggerConfig
+	// focused is the last window focused, also while paused
+	focused event.ActiveWindow
 }
 
 type TerminalDebounceInfo struct {
@@ -38,10 +40,16 @@ func (al *DebouncedActivityLogger) ActiveWindow(w event.ActiveWindow) {
 		return
 	}
 
+	al.mu.Lock()
+	al.focused = w
+	al.mu.Unlock()
+	autoPauser.ObserveWindow(w.Name, w.Title)
+
 	// Skip event processing if tracking is paused
-	if trackingPaused {
+	if pauseManager.Paused() {
 		return
 	}
+	daemonStatus.received.Add(1)
 
 	al.mu.Lock()
 	defer al.mu.Unlock()
@@ -57,6 +65,7 @@ func (al *DebouncedActivityLogger) ActiveWindow(w event.ActiveWindow) {
 	windowKey := w.Name + "|" + w.Title
 
 	if windowKey == al.lastWindow {
+		daemonStatus.debounced.Add(1)
 		return
 	}
 
@@ -67,6 +76,7 @@ func (al *DebouncedActivityLogger) ActiveWindow(w event.ActiveWindow) {
 		if lastLogExists && now.Sub(lastLogTime) < al.config.GeneralDebounceTime {
 			// Update the last window but don't log
 			al.lastWindow = windowKey
+			daemonStatus.debounced.Add(1)
 			return
 		}
 	}
@@ -84,6 +94,7 @@ func (al *DebouncedActivityLogger) ActiveWindow(w event.ActiveWindow) {
 		} else if now.Sub(termInfo.LastTime) < al.config.TerminalDebounceTime {
 			termInfo.LastTitle = w.Title
 			termInfo.LastTime = now
+			daemonStatus.debounced.Add(1)
 			return
 		} else {
 			termInfo.LastTitle = w.Title
@@ -100,6 +111,27 @@ func (al *DebouncedActivityLogger) ActiveWindow(w event.ActiveWindow) {
 	}
 }
 
+// Workspace follows the active workspace for the workspace rules of auto_pause
+func (al *DebouncedActivityLogger) Workspace(w event.WorkspaceName) {
+	autoPauser.ObserveWorkspace(string(w))
+}
+
+// Resumed logs the window focused when tracking resumes, since no event is sent for
+// a window that stays focused
+func (al *DebouncedActivityLogger) Resumed(at time.Time) {
+	al.mu.Lock()
+	defer al.mu.Unlock()
+	if al.focused.Name == "" && al.focused.Title == "" {
+		return
+	}
+	al.lastWindow = al.focused.Name + "|" + al.focused.Title
+	al.logChan <- LogEntry{
+		Timestamp: at,
+		EventType: string(event.EventActiveWindow),
+		EventData: al.focused,
+	}
+}
+
 func (al *DebouncedActivityLogger) getLastLogTimeForKey(windowKey string) (time.Time, bool) {
 	if isTerminal := IsTerminalEmulator(windowKey[:strings.Index(windowKey, "|")]); isTerminal {
 		terminalName := windowKey[:strings.Index(windowKey, "|")]
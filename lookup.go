@@ -0,0 +1,206 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/thiagokokada/hyprland-go/event"
+)
+
+const (
+	DefaultLookupSpan = 30 * time.Minute
+	// How far around the requested window events are loaded, so that intervals
+	// which started earlier or end later are reconstructed correctly
+	lookupMargin    = 24 * time.Hour
+	exactTimeFormat = "2006-01-02 15:04:05"
+)
+
+// Period is any span of time shown by the point-in-time lookup: a focus interval,
+// a manual entry, an idle/lock/suspend period, or a zero-length marker event
+type Period struct {
+	Kind  string
+	Start time.Time
+	End   time.Time
+	App   string
+	Title string
+}
+
+func (p Period) Contains(t time.Time) bool {
+	return !t.Before(p.Start) && t.Before(p.End)
+}
+
+// ParseTimeExpression parses the times accepted by -at and -around, relative to now:
+// RFC3339, "2026-10-14 14:32", "yesterday 10:00", "tuesday 14:32", "today 9:15" or
+// just "14:32" for today. Weekdays refer to the most recent such day, including today.
+func ParseTimeExpression(s string, now time.Time) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+
+	now = now.Local()
+	fields := strings.Fields(strings.ToLower(s))
+	if len(fields) == 0 || len(fields) > 2 {
+		return time.Time{}, fmt.Errorf("invalid time %q", s)
+	}
+
+	day := BucketStart(now, BucketDay)
+	clock := fields[len(fields)-1]
+	if len(fields) == 2 {
+		switch dayExpr := fields[0]; dayExpr {
+		case "today":
+		case "yesterday":
+			day = day.AddDate(0, 0, -1)
+		default:
+			if parsed, err := time.ParseInLocation("2006-01-02", dayExpr, time.Local); err == nil {
+				day = parsed
+				break
+			}
+			weekday, ok := parseWeekday(dayExpr)
+			if !ok {
+				return time.Time{}, fmt.Errorf("invalid day %q in %q", dayExpr, s)
+			}
+			day = day.AddDate(0, 0, -((int(now.Weekday()) - int(weekday) + 7) % 7))
+		}
+	}
+
+	for _, layout := range []string{"15:04:05", "15:04"} {
+		if t, err := time.Parse(layout, clock); err == nil {
+			return time.Date(day.Year(), day.Month(), day.Day(), t.Hour(), t.Minute(), t.Second(), 0, time.Local), nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid time of day %q in %q (expected HH:MM or HH:MM:SS)", clock, s)
+}
+
+func parseWeekday(s string) (time.Weekday, bool) {
+	for d := time.Sunday; d <= time.Saturday; d++ {
+		name := strings.ToLower(d.String())
+		if s == name || (len(s) >= 3 && strings.HasPrefix(name, s)) {
+			return d, true
+		}
+	}
+	return 0, false
+}
+
+// BuildPeriods merges focus intervals, manual entries, away periods and marker events
+// into one chronological list, clipped to [from, to). Periods still open are shown
+// until now.
+func BuildPeriods(entries []LogEntry, manual []ManualEntry, from, to time.Time) []Period {
+	var periods []Period
+	openEnd := minTime(to, time.Now())
+	intervals := ApplyManualEntries(BuildIntervals(entries, openEnd), manual, from, to)
+	for _, iv := range clipIntervals(intervals, from, to) {
+		// Repeated events for the same window are shown as a single period
+		if n := len(periods); n > 0 {
+			last := &periods[n-1]
+			if last.App == iv.App && last.Title == iv.Title && last.End.Equal(iv.Start) {
+				last.End = iv.End
+				continue
+			}
+		}
+		kind := "focus"
+		if iv.ManualID != 0 {
+			kind = "manual"
+		}
+		periods = append(periods, Period{Kind: kind, Start: iv.Start, End: iv.End, App: iv.App, Title: iv.Title})
+	}
+	for _, p := range BuildAwayPeriods(entries, openEnd) {
+		if !p.End.After(from) || !p.Start.Before(to) {
+			continue
+		}
+		periods = append(periods, Period{Kind: p.Kind, Start: maxTime(p.Start, from), End: minTime(p.End, to), Title: p.Reason})
+	}
+	for _, entry := range entries {
+		if entry.EventType == string(event.EventActiveWindow) {
+			continue
+		}
+		if _, _, ok := awayEventKind(entry.EventType); ok {
+			continue
+		}
+		if entry.Timestamp.Before(from) || !entry.Timestamp.Before(to) {
+			continue
+		}
+		periods = append(periods, Period{Kind: entry.EventType, Start: entry.Timestamp, End: entry.Timestamp, Title: entry.EventData.Title})
+	}
+
+	sort.SliceStable(periods, func(i, j int) bool {
+		return periods[i].Start.Before(periods[j].Start)
+	})
+	return periods
+}
+
+func minTime(a, b time.Time) time.Time {
+	if a.Before(b) {
+		return a
+	}
+	return b
+}
+
+func maxTime(a, b time.Time) time.Time {
+	if a.After(b) {
+		return a
+	}
+	return b
+}
+
+// RunLookup prints what happened around a point in time. With at set, the period
+// containing that instant is reported first.
+func RunLookup(dbPath string, timeExpr string, at bool, span time.Duration, format string) {
+	target, err := ParseTimeExpression(timeExpr, time.Now())
+	if err != nil {
+		log.Fatalf("Error: %v", err)
+	}
+
+	db, err := OpenDatabase(dbPath)
+	if err != nil {
+		log.Fatalf("Error opening database: %v", err)
+	}
+	defer db.Close()
+
+	from := target.Add(-span / 2)
+	to := target.Add(span / 2)
+	entries, err := db.GetEvents(from.Add(-lookupMargin), to.Add(lookupMargin))
+	if err != nil {
+		log.Fatalf("Error retrieving events from database: %v", err)
+	}
+	manual, err := db.GetManualEntries(from, to)
+	if err != nil {
+		log.Fatalf("Error retrieving manual entries from database: %v", err)
+	}
+	periods := BuildPeriods(entries, manual, from, to)
+
+	if at && format == FormatText {
+		fmt.Printf("At %s: %s\n", target.Format("Mon "+exactTimeFormat), describePeriodAt(periods, target))
+	}
+
+	table := &ReportTable{
+		Title:   fmt.Sprintf("Activity from %s to %s", from.Format(exactTimeFormat), to.Format(exactTimeFormat)),
+		Columns: []string{"Start", "End", "Duration", "Kind", "Application", "Title"},
+	}
+	for _, p := range periods {
+		table.AddRow(p.Start, p.End, p.End.Sub(p.Start), p.Kind, p.App, p.Title)
+	}
+	if err := RenderTables(os.Stdout, format, table); err != nil {
+		log.Fatalf("Error writing report: %v", err)
+	}
+}
+
+func describePeriodAt(periods []Period, t time.Time) string {
+	for _, p := range periods {
+		if (p.Kind != "focus" && p.Kind != "manual") || !p.Contains(t) {
+			continue
+		}
+		return fmt.Sprintf("%s - %s (focused %s to %s)", p.App, p.Title, p.Start.Format("15:04:05"), p.End.Format("15:04:05"))
+	}
+	for _, p := range periods {
+		if p.Start.Equal(p.End) || !p.Contains(t) {
+			continue
+		}
+		return fmt.Sprintf("%s (%s to %s)", p.Kind, p.Start.Format("15:04:05"), p.End.Format("15:04:05"))
+	}
+	return "nothing was recorded"
+}
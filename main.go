@@ -2,9 +2,11 @@ package main
 
 import (
 	"flag"
+	"fmt"
 	"log"
 	"os"
 	"path/filepath"
+	"sync"
 	"time"
 
 	"fyne.io/systray"
@@ -13,27 +15,79 @@ import (
 )
 
 func main() {
+	if len(os.Args) > 1 {
+		if command, ok := FindCommand(os.Args[1]); ok {
+			if err := command.Run(os.Args[2:]); err != nil {
+				log.Fatalf("Error: %v", err)
+			}
+			return
+		}
+	}
+
 	// Common flags
 	dbPathFlag := flag.String("db-path", DefaultDBPath, "Path to the SQLite database file")
+	configFlag := flag.String("config", GetDefaultConfigPath(), "Path to the JSON configuration file (categories, ...)")
 
 	// Daemon mode flags
 	daemonFlag := flag.Bool("daemon", false, "Run as a daemon to collect window activity")
 	terminalDebounceFlag := flag.Int("terminal-debounce", int(DebounceTime.Seconds()), "Terminal debounce time in seconds")
 	generalDebounceFlag := flag.Int("general-debounce", int(DefaultGeneralDebounceTime.Seconds()), "General debounce time in seconds (default: 0.5)")
 	systrayFlag := flag.Bool("systray", true, "Enable system tray icon for controlling the daemon")
+	replaceFlag := flag.Bool("replace", false, "Ask a daemon already running to shut down and take over from it")
 	
 	// Analysis mode flags
 	keywordsFlag := flag.String("keywords", "", "Comma-separated list of keywords to filter related activities (e.g., \"firefox,projectX,mydoc\")")
 	minDurationFlag := flag.Int("min-duration", 60, "Minimum duration in seconds to include in the output (e.g., 1 will filter out activities less than 1 second)")
+	perVisitFlag := flag.Bool("per-visit", false, "Apply -min-duration to each visit of an application instead of its total time")
 	appOnlyFlag := flag.Bool("app-only", false, "Only display per-application report, skip window details")
-	timeRangeFlag := flag.String("time-range", "month", "Time range for analysis: 'day', 'week', 'month', 'year', or 'all'")
+	timeRangeFlag := flag.String("time-range", "month", "Time range for analysis: 'day', 'week', 'month', 'year', 'all' or an explicit range YYYY-MM-DD..YYYY-MM-DD")
+	reportFlag := flag.String("report", "summary", "Report to generate: 'summary', 'pivot' (time per bucket), 'timeline' (one day), 'heatmap' (weekday × hour), 'calendar' (last year), 'sessions' (clock-in/clock-out per day), 'focus' (context switches and deep work), 'stats' (visit length statistics), 'tags' (time per tag), 'timesheet' (billable hours per project), 'goals' (progress toward goals and limits), 'breaks' (break reminders taken and ignored) or 'focus-sessions' (focus sessions and their distractions)")
+	bucketFlag := flag.String("bucket", "day", "Bucket size for the pivot report: 'hour', 'day', 'week' or 'month'")
+	groupByFlag := flag.String("group-by", "app", "Grouping for pivot and timeline reports: 'app', 'category', 'window' or 'tag'")
+	dateFlag := flag.String("date", "", "Day shown by the timeline report, as YYYY-MM-DD (default: today)")
+	breakThresholdFlag := flag.Duration("break-threshold", DefaultBreakThreshold, "Inactivity longer than this starts a new work session in the sessions report")
+	deepWorkMinFlag := flag.Duration("deep-work-min", DefaultDeepWorkMin, "Minimum length of a deep work block in the focus report")
+	deepWorkCategoriesFlag := flag.String("deep-work-categories", "", "Comma-separated categories that count as deep work (default: all)")
+	deepWorkInterruptionsFlag := flag.Int("deep-work-interruptions", DefaultDeepWorkInterruptions, "Maximum number of short interruptions within a deep work block")
+	deepWorkInterruptionMaxFlag := flag.Duration("deep-work-interruption-max", DefaultDeepWorkInterruptionMax, "Longest switch away that still counts as a short interruption")
+	compareFlag := flag.String("compare", "", "Compare the summary with 'previous' (the preceding range of equal length) or an explicit range YYYY-MM-DD..YYYY-MM-DD")
+	formatFlag := flag.String("format", FormatText, "Output format for reports: 'text', 'csv', 'markdown' or 'json'")
+
+	// Point-in-time lookup
+	atFlag := flag.String("at", "", "Show what was focused at a time, e.g. \"14:32\", \"tuesday 14:32\" or \"2026-10-14 14:32\"")
+	aroundFlag := flag.String("around", "", "Show all activity around a time (see -at for the accepted formats)")
+	spanFlag := flag.Duration("span", DefaultLookupSpan, "Length of the window shown by -at and -around, centered on the given time")
+
 	
 	// External idle manager integration
 	idleSignalFlag := flag.String("idle-signal", "", "Send idle signal to running daemon: 'start' to mark idle start, 'end' to mark idle end")
+	lockSignalFlag := flag.String("lock-signal", "", "Send screen lock signal to running daemon: 'start' when locking, 'end' when unlocked")
+	suspendSignalFlag := flag.String("suspend-signal", "", "Send suspend signal to running daemon: 'start' before sleep, 'end' after resume")
 	
 	// Toggle pause via command line
 	togglePauseFlag := flag.Bool("toggle-pause", false, "Toggle pause/resume on a running daemon")
 
+	// Tagging
+	tagFlag := flag.String("tag", "", "Tag the current and following activity on a running daemon, e.g. \"ticket-4821\"")
+	clearTagFlag := flag.Bool("clear-tag", false, "Clear the tag on a running daemon")
+	filterTagFlag := flag.String("filter-tag", "", "Comma-separated tags (glob patterns, 'untagged' for none) to restrict reports to")
+
+	// Enforcement
+	overrideFlag := flag.String("override", "", "Suspend the enforcement rules on a running daemon for a duration, e.g. \"15m\", or 'off' to lift the override")
+
+	// Focus sessions
+	focusFlag := flag.String("focus", "", "Start a focus session of the given length on a running daemon, e.g. \"25m\", or 'stop' to end it")
+	focusCategoryFlag := flag.String("focus-category", "", "Category the focus session started with -focus is bound to (default: from the configuration)")
+
+	// Status bar module
+	barFlag := flag.Bool("bar", false, "Run as a Waybar/Eww custom module, printing a JSON line whenever the daemon's state changes")
+	barTargetFlag := flag.Duration("bar-target", DefaultBarTarget, "Active time per day shown as 100% by -bar")
+
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage of %s:\n", filepath.Base(os.Args[0]))
+		flag.PrintDefaults()
+		PrintCommandUsage()
+	}
 	flag.Parse()
 
 	// Handle special command flags
@@ -44,10 +98,68 @@ func main() {
 		return
 	}
 	
+	if *lockSignalFlag != "" {
+		if err := SendAwaySignal("lock", *lockSignalFlag); err != nil {
+			log.Fatalf("Error sending lock signal: %v", err)
+		}
+		return
+	}
+
+	if *suspendSignalFlag != "" {
+		if err := SendAwaySignal("suspend", *suspendSignalFlag); err != nil {
+			log.Fatalf("Error sending suspend signal: %v", err)
+		}
+		return
+	}
+
+	if *tagFlag != "" || *clearTagFlag {
+		if err := SendTagSignal(*tagFlag); err != nil {
+			log.Fatalf("Error sending tag: %v", err)
+		}
+		return
+	}
+
+	if *overrideFlag != "" {
+		if err := SendOverrideSignal(*overrideFlag); err != nil {
+			log.Fatalf("Error sending override: %v", err)
+		}
+		return
+	}
+
+	if *focusFlag != "" {
+		if err := SendFocusSignal(*focusFlag, *focusCategoryFlag); err != nil {
+			log.Fatalf("Error sending focus command: %v", err)
+		}
+		return
+	}
+
+	if *barFlag {
+		RunBar(*barTargetFlag)
+		return
+	}
+
 	if *togglePauseFlag {
-		if err := SendPauseToggleSignal(); err != nil {
+		paused, err := SendPauseToggleSignal()
+		if err != nil {
 			log.Fatalf("Error sending pause toggle signal: %v", err)
 		}
+		if paused {
+			fmt.Println("Tracking paused")
+		} else {
+			fmt.Println("Tracking resumed")
+		}
+		return
+	}
+
+	if *atFlag != "" || *aroundFlag != "" {
+		if err := ValidateOutputFormat(*formatFlag); err != nil {
+			log.Fatalf("Error: %v", err)
+		}
+		if *atFlag != "" {
+			RunLookup(*dbPathFlag, *atFlag, true, *spanFlag, *formatFlag)
+		} else {
+			RunLookup(*dbPathFlag, *aroundFlag, false, *spanFlag, *formatFlag)
+		}
 		return
 	}
 
@@ -59,16 +171,61 @@ func main() {
 			}
 		}
 
+		userConfig, err := LoadConfig(*configFlag)
+		if err != nil {
+			log.Fatalf("Error loading configuration: %v", err)
+		}
+
 		config := LoggerConfig{
 			TerminalDebounceTime:   time.Duration(*terminalDebounceFlag) * time.Second,
 			GeneralDebounceTime:    time.Duration(*generalDebounceFlag) * time.Second,
 			EnableSystray:          *systrayFlag,
 			DBPath:                 *dbPathFlag,
+			Config:                 userConfig,
+			Replace:                *replaceFlag,
 		}
 		RunDaemonWithConfig(config)
 	} else {
-		minDuration := time.Duration(*minDurationFlag) * time.Second
-		RunAnalysis(*dbPathFlag, *keywordsFlag, minDuration, *appOnlyFlag, *timeRangeFlag)
+		bucket, err := ParseBucketUnit(*bucketFlag)
+		if err != nil {
+			log.Fatalf("Error: %v", err)
+		}
+		if err := ValidateOutputFormat(*formatFlag); err != nil {
+			log.Fatalf("Error: %v", err)
+		}
+		if err := ValidateGroupBy(*groupByFlag); err != nil {
+			log.Fatalf("Error: %v", err)
+		}
+		userConfig, err := LoadConfig(*configFlag)
+		if err != nil {
+			log.Fatalf("Error loading configuration: %v", err)
+		}
+
+		config := AnalysisConfig{
+			DBPath:      *dbPathFlag,
+			Keywords:    ParseKeywords(*keywordsFlag),
+			MinDuration: time.Duration(*minDurationFlag) * time.Second,
+			AppOnly:     *appOnlyFlag,
+			TimeRange:   *timeRangeFlag,
+			Report:      *reportFlag,
+			Bucket:      bucket,
+			Format:      *formatFlag,
+			GroupBy:     *groupByFlag,
+			Date:        *dateFlag,
+			Compare:     *compareFlag,
+			Config:      userConfig,
+			TagFilter:   ParseKeywords(*filterTagFlag),
+
+			MinDurationPerVisit: *perVisitFlag,
+			BreakThreshold:      *breakThresholdFlag,
+			DeepWork: DeepWorkRules{
+				MinDuration:      *deepWorkMinFlag,
+				Categories:       ParseKeywords(*deepWorkCategoriesFlag),
+				MaxInterruptions: *deepWorkInterruptionsFlag,
+				InterruptionMax:  *deepWorkInterruptionMaxFlag,
+			},
+		}
+		RunAnalysis(config)
 	}
 }
 
@@ -78,10 +235,34 @@ var iconData []byte
 
 // Global variables to control tracking state
 var (
-	trackingPaused bool
-	pauseMenuItem  *systray.MenuItem
+	// pauseManager pauses and resumes the tracking, nil outside of the daemon, and
+	// autoPauser does so by itself when auto_pause is configured
+	pauseManager     *PauseManager
+	autoPauser       *AutoPauser
+	statusMenuItem   *systray.MenuItem
+	pauseMenuItem    *systray.MenuItem
+	pauseForMenuItem *systray.MenuItem
 	quitAppChan    = make(chan struct{})
+	// shutdownChan receives the shutdown requests sent over the control socket
+	shutdownChan   = make(chan struct{}, 1)
 	systrayEnabled bool
+
+	// activeTag is the label set with -tag, shown in the tray menu
+	activeTag   string
+	tagMu       sync.Mutex
+	tagMenuItem *systray.MenuItem
+
+	// Progress toward the current goals, one submenu item per goal
+	goalsMenuItem *systray.MenuItem
+	goalMenuItems []*systray.MenuItem
+	goalMenuMu    sync.Mutex
+
+	// focusSessions runs the focus sessions of the daemon, nil outside of it
+	focusSessions *FocusSessionManager
+	focusMenuItem *systray.MenuItem
+
+	// enforcer acts on blocked windows, nil unless enforcement rules are configured
+	enforcer *Enforcer
 )
 
 func systrayOnReady() {
@@ -90,10 +271,34 @@ func systrayOnReady() {
 	systray.SetTooltip("Hyprland Activity Tracker")
 	
 	// Status section
-	mStatus := systray.AddMenuItem("Status: Active", "Current tracking status")
-	mStatus.Disable()
+	statusMenuItem = systray.AddMenuItem("Status: Active", "Current tracking status")
+	statusMenuItem.Disable()
 	
 	pauseMenuItem = systray.AddMenuItem("Pause Tracking", "Pause activity tracking")
+	pauseForMenuItem = systray.AddMenuItem("Pause For", "Pause activity tracking for a while")
+	pauseLengths := []time.Duration{15 * time.Minute, 30 * time.Minute, time.Hour}
+	for _, length := range pauseLengths {
+		item := pauseForMenuItem.AddSubMenuItem(FormatDuration(length), "Resume tracking after "+FormatDuration(length))
+		go func(length time.Duration) {
+			for range item.ClickedCh {
+				if _, err := pauseManager.Pause(time.Now().Add(length), ""); err != nil {
+					log.Printf("Error pausing tracking: %v", err)
+				}
+			}
+		}(length)
+	}
+	updatePauseMenu(pauseManager.State())
+
+	tagMenuItem = systray.AddMenuItem("", "Tag set with -tag, cleared with -clear-tag")
+	tagMenuItem.Disable()
+	setActiveTag(currentTag())
+
+	focusMenuItem = systray.AddMenuItem("Start Focus Session", "Start or stop a focus session")
+
+	goalMenuMu.Lock()
+	goalsMenuItem = systray.AddMenuItem("Goals", "Progress toward today's goals and limits")
+	goalsMenuItem.Hide()
+	goalMenuMu.Unlock()
 	
 	systray.AddSeparator()
 	
@@ -103,16 +308,18 @@ func systrayOnReady() {
 	// Handle menu item clicks in goroutines
 	go func() {
 		for range pauseMenuItem.ClickedCh {
-			if trackingPaused {
-				resumeTracking()
-				mStatus.SetTitle("Status: Active")
-			} else {
-				pauseTracking()
-				mStatus.SetTitle("Status: Paused")
+			if _, err := pauseManager.Toggle(); err != nil {
+				log.Printf("Error pausing tracking: %v", err)
 			}
 		}
 	}()
 	
+	go func() {
+		for range focusMenuItem.ClickedCh {
+			focusSessions.Toggle()
+		}
+	}()
+
 	go func() {
 		for range mQuit.ClickedCh {
 			log.Println("Quit selected from systray menu")
@@ -121,35 +328,76 @@ func systrayOnReady() {
 	}()
 }
 
-func pauseTracking() {
-	trackingPaused = true
-	log.Println("Activity tracking paused")
-	
-	// Update systray if enabled
-	if systrayEnabled {
+// updatePauseMenu shows whether tracking is paused in the tray menu
+func updatePauseMenu(state PauseResult) {
+	if !systrayEnabled || pauseMenuItem == nil {
+		return
+	}
+	if state.Paused {
+		statusMenuItem.SetTitle("Status: " + state.Describe())
 		pauseMenuItem.SetTitle("Resume Tracking")
 		pauseMenuItem.SetTooltip("Resume activity tracking")
+		pauseForMenuItem.Hide()
 		systray.SetTooltip("HyprTracker (Paused)")
+	} else {
+		statusMenuItem.SetTitle("Status: Active")
+		pauseMenuItem.SetTitle("Pause Tracking")
+		pauseMenuItem.SetTooltip("Pause activity tracking")
+		pauseForMenuItem.Show()
+		systray.SetTooltip("HyprTracker (Active)")
 	}
 }
 
-func resumeTracking() {
-	trackingPaused = false
-	log.Println("Activity tracking resumed")
-	
+func currentTag() string {
+	tagMu.Lock()
+	defer tagMu.Unlock()
+	return activeTag
+}
+
+func setActiveTag(tag string) {
+	tagMu.Lock()
+	activeTag = tag
+	tagMu.Unlock()
+
 	// Update systray if enabled
-	if systrayEnabled {
-		pauseMenuItem.SetTitle("Pause Tracking")
-		pauseMenuItem.SetTooltip("Pause activity tracking")
-		systray.SetTooltip("HyprTracker (Active)")
+	if systrayEnabled && tagMenuItem != nil {
+		if tag == "" {
+			tagMenuItem.Hide()
+		} else {
+			tagMenuItem.SetTitle("Tag: " + tag)
+			tagMenuItem.Show()
+		}
 	}
 }
 
-func toggleTracking() {
-	if trackingPaused {
-		resumeTracking()
-	} else {
-		pauseTracking()
+func updateGoalMenu(progress []GoalProgress) {
+	goalMenuMu.Lock()
+	defer goalMenuMu.Unlock()
+
+	if !systrayEnabled || goalsMenuItem == nil {
+		return
+	}
+	for len(goalMenuItems) < len(progress) {
+		item := goalsMenuItem.AddSubMenuItem("", "")
+		item.Disable()
+		goalMenuItems = append(goalMenuItems, item)
+	}
+	for i, item := range goalMenuItems {
+		if i >= len(progress) {
+			item.Hide()
+			continue
+		}
+		item.SetTitle(progress[i].Label() + " – " + progress[i].Status(time.Now()))
+		item.Show()
+	}
+	if len(progress) > 0 {
+		goalsMenuItem.Show()
+	}
+}
+
+func updateFocusMenu(label string) {
+	if systrayEnabled && focusMenuItem != nil {
+		focusMenuItem.SetTitle(label)
 	}
 }
 
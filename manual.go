@@ -0,0 +1,319 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"sort"
+	"time"
+)
+
+const (
+	// ManualApp is the application name under which manual entries are reported
+	ManualApp = "manual"
+	// ManualSourceCLI marks entries created with the add/edit/split commands
+	ManualSourceCLI = "cli"
+)
+
+// ManualEntry is a span of time entered by hand, e.g. a meeting away from the computer.
+// An excluded entry only removes the tracked time it covers, e.g. a movie left playing.
+// Source, CreatedAt, UpdatedAt and SplitFrom record where the entry came from.
+type ManualEntry struct {
+	ID        int64
+	Start     time.Time
+	End       time.Time
+	Label     string
+	Category  string
+	Source    string
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	SplitFrom int64
+	Excluded  bool
+}
+
+func (m ManualEntry) Duration() time.Duration {
+	return m.End.Sub(m.Start)
+}
+
+// Interval returns the entry as a focus interval of the "manual" application
+func (m ManualEntry) Interval() Interval {
+	return Interval{
+		Start:    m.Start,
+		End:      m.End,
+		App:      ManualApp,
+		Title:    m.Label,
+		Category: m.Category,
+		ManualID: m.ID,
+	}
+}
+
+// ApplyManualEntries removes the tracked time covered by manual entries and adds the
+// entries that are not excluded, clipped to [from, to). A zero from or to leaves that
+// side open.
+func ApplyManualEntries(intervals []Interval, entries []ManualEntry, from, to time.Time) []Interval {
+	if len(entries) == 0 {
+		return intervals
+	}
+
+	var merged []Interval
+	for _, iv := range intervals {
+		pieces := []Interval{iv}
+		for _, m := range entries {
+			var remaining []Interval
+			for _, p := range pieces {
+				remaining = append(remaining, subtractRange(p, m.Start, m.End)...)
+			}
+			pieces = remaining
+		}
+		merged = append(merged, pieces...)
+	}
+
+	for _, m := range entries {
+		if m.Excluded {
+			continue
+		}
+		iv := m.Interval()
+		if !from.IsZero() && iv.Start.Before(from) {
+			iv.Start = from
+		}
+		if !to.IsZero() && iv.End.After(to) {
+			iv.End = to
+		}
+		if iv.End.After(iv.Start) {
+			merged = append(merged, iv)
+		}
+	}
+
+	sort.SliceStable(merged, func(i, j int) bool {
+		return merged[i].Start.Before(merged[j].Start)
+	})
+	return merged
+}
+
+// subtractRange returns the parts of iv outside [start, end)
+func subtractRange(iv Interval, start, end time.Time) []Interval {
+	if !iv.Start.Before(end) || !iv.End.After(start) {
+		return []Interval{iv}
+	}
+	var parts []Interval
+	if iv.Start.Before(start) {
+		before := iv
+		before.End = start
+		parts = append(parts, before)
+	}
+	if iv.End.After(end) {
+		after := iv
+		after.Start = end
+		parts = append(parts, after)
+	}
+	return parts
+}
+
+const manualEntryColumns = `id, start_time, end_time, label, category, source, created_at, updated_at, split_from, excluded`
+
+func scanManualEntry(scan func(dest ...any) error) (ManualEntry, error) {
+	var m ManualEntry
+	var start, end, created, updated string
+	var category sql.NullString
+	var splitFrom sql.NullInt64
+	if err := scan(&m.ID, &start, &end, &m.Label, &category, &m.Source, &created, &updated, &splitFrom, &m.Excluded); err != nil {
+		return m, err
+	}
+	m.Category = category.String
+	m.SplitFrom = splitFrom.Int64
+
+	for _, field := range []struct {
+		value string
+		dest  *time.Time
+	}{{start, &m.Start}, {end, &m.End}, {created, &m.CreatedAt}, {updated, &m.UpdatedAt}} {
+		t, err := time.Parse(time.RFC3339, field.value)
+		if err != nil {
+			return m, fmt.Errorf("timestamp parse failed: %v", err)
+		}
+		*field.dest = t
+	}
+	return m, nil
+}
+
+// GetManualEntries returns the manual entries overlapping [startTime, endTime], oldest first
+func (d *Database) GetManualEntries(startTime, endTime time.Time) ([]ManualEntry, error) {
+	rows, err := d.db.Query(`
+		SELECT `+manualEntryColumns+`
+		FROM manual_entries
+		WHERE end_time > ? AND start_time < ?
+		ORDER BY start_time
+	`, startTime.Format(time.RFC3339), endTime.Format(time.RFC3339))
+	if err != nil {
+		return nil, fmt.Errorf("query failed: %v", err)
+	}
+	defer rows.Close()
+
+	var entries []ManualEntry
+	for rows.Next() {
+		m, err := scanManualEntry(rows.Scan)
+		if err != nil {
+			return nil, fmt.Errorf("row scan failed: %v", err)
+		}
+		entries = append(entries, m)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("row iteration failed: %v", err)
+	}
+	return entries, nil
+}
+
+// GetManualEntry returns the manual entry with the given id
+func (d *Database) GetManualEntry(id int64) (ManualEntry, error) {
+	row := d.db.QueryRow(`SELECT `+manualEntryColumns+` FROM manual_entries WHERE id = ?`, id)
+	m, err := scanManualEntry(row.Scan)
+	if err == sql.ErrNoRows {
+		return m, fmt.Errorf("no manual entry #%d", id)
+	}
+	if err != nil {
+		return m, fmt.Errorf("failed to read manual entry #%d: %v", id, err)
+	}
+	return m, nil
+}
+
+// manualQuerier is a *sql.DB or a *sql.Tx, so that entries can be changed together
+type manualQuerier interface {
+	Exec(query string, args ...any) (sql.Result, error)
+	QueryRow(query string, args ...any) *sql.Row
+}
+
+// checkManualOverlap fails if [start, end) overlaps a manual entry other than ignoreID
+func checkManualOverlap(q manualQuerier, start, end time.Time, ignoreID int64) error {
+	var id int64
+	var label string
+	err := q.QueryRow(`
+		SELECT id, label FROM manual_entries
+		WHERE end_time > ? AND start_time < ? AND id != ?
+		ORDER BY start_time
+		LIMIT 1
+	`, start.Format(time.RFC3339), end.Format(time.RFC3339), ignoreID).Scan(&id, &label)
+	switch {
+	case err == sql.ErrNoRows:
+		return nil
+	case err != nil:
+		return fmt.Errorf("failed to check for overlapping entries: %v", err)
+	default:
+		return fmt.Errorf("overlaps manual entry #%d (%s)", id, label)
+	}
+}
+
+func validateManualEntry(m ManualEntry) error {
+	if m.Label == "" && !m.Excluded {
+		return fmt.Errorf("a label is required")
+	}
+	if !m.End.After(m.Start) {
+		return fmt.Errorf("end %s is not after start %s", m.End.Format(exactTimeFormat), m.Start.Format(exactTimeFormat))
+	}
+	return nil
+}
+
+// AddManualEntry stores a new manual entry and returns it with its id set
+func (d *Database) AddManualEntry(m ManualEntry) (ManualEntry, error) {
+	return insertManualEntry(d.db, m)
+}
+
+func insertManualEntry(q manualQuerier, m ManualEntry) (ManualEntry, error) {
+	if err := validateManualEntry(m); err != nil {
+		return m, err
+	}
+	if err := checkManualOverlap(q, m.Start, m.End, 0); err != nil {
+		return m, err
+	}
+
+	now := time.Now()
+	m.CreatedAt, m.UpdatedAt = now, now
+	var splitFrom any
+	if m.SplitFrom != 0 {
+		splitFrom = m.SplitFrom
+	}
+	result, err := q.Exec(`
+		INSERT INTO manual_entries (start_time, end_time, label, category, source, created_at, updated_at, split_from, excluded)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, m.Start.Format(time.RFC3339), m.End.Format(time.RFC3339), m.Label, m.Category, m.Source,
+		now.Format(time.RFC3339), now.Format(time.RFC3339), splitFrom, m.Excluded)
+	if err != nil {
+		return m, fmt.Errorf("failed to insert manual entry: %v", err)
+	}
+	if m.ID, err = result.LastInsertId(); err != nil {
+		return m, fmt.Errorf("failed to get id of manual entry: %v", err)
+	}
+	return m, nil
+}
+
+// UpdateManualEntry overwrites the times, label, category and exclusion of an existing entry
+func (d *Database) UpdateManualEntry(m ManualEntry) (ManualEntry, error) {
+	return updateManualEntry(d.db, m)
+}
+
+func updateManualEntry(q manualQuerier, m ManualEntry) (ManualEntry, error) {
+	if err := validateManualEntry(m); err != nil {
+		return m, err
+	}
+	if err := checkManualOverlap(q, m.Start, m.End, m.ID); err != nil {
+		return m, err
+	}
+
+	m.UpdatedAt = time.Now()
+	_, err := q.Exec(`
+		UPDATE manual_entries
+		SET start_time = ?, end_time = ?, label = ?, category = ?, excluded = ?, updated_at = ?
+		WHERE id = ?
+	`, m.Start.Format(time.RFC3339), m.End.Format(time.RFC3339), m.Label, m.Category, m.Excluded, m.UpdatedAt.Format(time.RFC3339), m.ID)
+	if err != nil {
+		return m, fmt.Errorf("failed to update manual entry #%d: %v", m.ID, err)
+	}
+	return m, nil
+}
+
+// SplitManualEntry cuts an entry in two at the given time. The original entry keeps
+// the first part; the second part is a new entry that records which entry it came from.
+func (d *Database) SplitManualEntry(id int64, at time.Time) (ManualEntry, ManualEntry, error) {
+	first, err := d.GetManualEntry(id)
+	if err != nil {
+		return first, ManualEntry{}, err
+	}
+	if !at.After(first.Start) || !at.Before(first.End) {
+		return first, ManualEntry{}, fmt.Errorf("%s is not within manual entry #%d (%s to %s)",
+			at.Format(exactTimeFormat), id, first.Start.Local().Format(exactTimeFormat), first.End.Local().Format(exactTimeFormat))
+	}
+
+	second := first
+	second.ID = 0
+	second.Start = at
+	second.SplitFrom = id
+	second.Source = ManualSourceCLI
+	first.End = at
+
+	tx, err := d.db.Begin()
+	if err != nil {
+		return first, second, fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	// Shorten the original first so that the two halves do not overlap
+	if first, err = updateManualEntry(tx, first); err != nil {
+		return first, second, err
+	}
+	if second, err = insertManualEntry(tx, second); err != nil {
+		return first, second, err
+	}
+	if err := tx.Commit(); err != nil {
+		return first, second, fmt.Errorf("failed to commit split of manual entry #%d: %v", id, err)
+	}
+	return first, second, nil
+}
+
+// DeleteManualEntry removes a manual entry, so tracked time shows through again
+func (d *Database) DeleteManualEntry(id int64) error {
+	result, err := d.db.Exec(`DELETE FROM manual_entries WHERE id = ?`, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete manual entry #%d: %v", id, err)
+	}
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		return fmt.Errorf("no manual entry #%d", id)
+	}
+	return nil
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"sync"
+
+	"github.com/godbus/dbus/v5"
+)
+
+const (
+	notificationsService   = "org.freedesktop.Notifications"
+	notificationsPath      = "/org/freedesktop/Notifications"
+	notificationsInterface = "org.freedesktop.Notifications"
+)
+
+// Notifier sends desktop notifications over the org.freedesktop.Notifications
+// D-Bus interface. A nil Notifier silently drops notifications.
+type Notifier struct {
+	conn *dbus.Conn
+	mu   sync.Mutex
+	// Notifications replaced by later ones with the same key, e.g. one per goal
+	replaces map[string]uint32
+	// Callbacks of the notifications showing actions, by notification id
+	actions map[uint32]func(action string)
+}
+
+// NewNotifier connects to the session bus
+func NewNotifier() (*Notifier, error) {
+	conn, err := dbus.ConnectSessionBus()
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to session bus: %v", err)
+	}
+	n := &Notifier{conn: conn, replaces: make(map[string]uint32), actions: make(map[uint32]func(string))}
+
+	if err := conn.AddMatchSignal(dbus.WithMatchObjectPath(notificationsPath), dbus.WithMatchInterface(notificationsInterface)); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to subscribe to notification signals: %v", err)
+	}
+	signals := make(chan *dbus.Signal, 10)
+	conn.Signal(signals)
+	go n.handleSignals(signals)
+
+	return n, nil
+}
+
+// handleSignals runs the callbacks of invoked actions until the connection is closed
+func (n *Notifier) handleSignals(signals <-chan *dbus.Signal) {
+	for signal := range signals {
+		if len(signal.Body) < 2 {
+			continue
+		}
+		id, _ := signal.Body[0].(uint32)
+
+		n.mu.Lock()
+		callback := n.actions[id]
+		if signal.Name == notificationsInterface+".NotificationClosed" {
+			delete(n.actions, id)
+		}
+		n.mu.Unlock()
+
+		if action, ok := signal.Body[1].(string); ok && callback != nil && signal.Name == notificationsInterface+".ActionInvoked" {
+			callback(action)
+		}
+	}
+}
+
+func (n *Notifier) Close() error {
+	if n == nil {
+		return nil
+	}
+	return n.conn.Close()
+}
+
+// Notify shows a notification. Notifications sent with the same non-empty key
+// replace each other instead of piling up.
+func (n *Notifier) Notify(key, summary, body string) {
+	n.NotifyWithActions(key, summary, body, nil, nil)
+}
+
+// NotifyWithActions shows a notification with buttons, given as pairs of action key
+// and label. onAction is called with the key of the button clicked.
+func (n *Notifier) NotifyWithActions(key, summary, body string, actions []string, onAction func(action string)) {
+	if n == nil {
+		return
+	}
+
+	if actions == nil {
+		actions = []string{}
+	}
+	n.mu.Lock()
+	replaces := n.replaces[key]
+	n.mu.Unlock()
+
+	// The call blocks until the notification server answers, so it is made without
+	// holding the lock that handleSignals needs
+	var id uint32
+	call := n.conn.Object(notificationsService, notificationsPath).Call(notificationsInterface+".Notify", 0,
+		"HyprTracker", replaces, "", summary, body, actions, map[string]dbus.Variant{}, int32(-1))
+	if err := call.Store(&id); err != nil {
+		log.Printf("Error sending notification %q: %v", summary, err)
+		return
+	}
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if key != "" {
+		n.replaces[key] = id
+	}
+	if onAction != nil {
+		n.actions[id] = onAction
+	} else {
+		delete(n.actions, id)
+	}
+}
@@ -0,0 +1,240 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	FormatText     = "text"
+	FormatCSV      = "csv"
+	FormatMarkdown = "markdown"
+	FormatJSON     = "json"
+)
+
+func ValidateOutputFormat(format string) error {
+	switch format {
+	case FormatText, FormatCSV, FormatMarkdown, FormatJSON:
+		return nil
+	default:
+		return fmt.Errorf("invalid output format %q (must be 'text', 'csv', 'markdown' or 'json')", format)
+	}
+}
+
+// ReportTable is a titled table that can be rendered in any of the output formats.
+// Cells may be strings, numbers or time.Duration values; durations are shown with
+// FormatDuration in human formats and as whole seconds in machine formats. A nil
+// cell has no value: "n/a" in human formats, empty in CSV and null in JSON.
+type ReportTable struct {
+	Title   string   `json:"title"`
+	Columns []string `json:"columns"`
+	Rows    [][]any  `json:"rows"`
+}
+
+func (t *ReportTable) AddRow(cells ...any) {
+	t.Rows = append(t.Rows, cells)
+}
+
+// RenderTables writes the tables to w in the given format. JSON output is a single
+// array so that multi-section reports stay machine readable.
+func RenderTables(w io.Writer, format string, tables ...*ReportTable) error {
+	switch format {
+	case FormatJSON:
+		encoded := make([]ReportTable, len(tables))
+		for i, t := range tables {
+			encoded[i] = ReportTable{Title: t.Title, Columns: t.Columns, Rows: make([][]any, len(t.Rows))}
+			for r, row := range t.Rows {
+				encoded[i].Rows[r] = make([]any, len(row))
+				for c, cell := range row {
+					encoded[i].Rows[r][c] = machineCell(cell)
+				}
+			}
+		}
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(encoded)
+	case FormatCSV:
+		cw := csv.NewWriter(w)
+		for i, t := range tables {
+			if i > 0 {
+				if err := cw.Write(nil); err != nil {
+					return err
+				}
+			}
+			if len(tables) > 1 {
+				if err := cw.Write([]string{t.Title}); err != nil {
+					return err
+				}
+			}
+			if err := cw.Write(t.Columns); err != nil {
+				return err
+			}
+			for _, row := range t.Rows {
+				record := make([]string, len(row))
+				for c, cell := range row {
+					if cell != nil {
+						record[c] = fmt.Sprint(machineCell(cell))
+					}
+				}
+				if err := cw.Write(record); err != nil {
+					return err
+				}
+			}
+		}
+		cw.Flush()
+		return cw.Error()
+	case FormatMarkdown:
+		for i, t := range tables {
+			if i > 0 {
+				fmt.Fprintln(w)
+			}
+			if t.Title != "" {
+				fmt.Fprintf(w, "### %s\n\n", t.Title)
+			}
+			fmt.Fprintf(w, "| %s |\n", strings.Join(escapeMarkdownCells(t.Columns), " | "))
+			separators := make([]string, len(t.Columns))
+			for c := range separators {
+				separators[c] = "---"
+			}
+			fmt.Fprintf(w, "| %s |\n", strings.Join(separators, " | "))
+			for _, row := range t.Rows {
+				fmt.Fprintf(w, "| %s |\n", strings.Join(escapeMarkdownCells(humanCells(row)), " | "))
+			}
+		}
+		return nil
+	default:
+		for _, t := range tables {
+			if t.Title != "" {
+				fmt.Fprintf(w, "\n--- %s ---\n", t.Title)
+			}
+			writeTextTable(w, t)
+		}
+		return nil
+	}
+}
+
+func writeTextTable(w io.Writer, t *ReportTable) {
+	if len(t.Rows) == 0 {
+		fmt.Fprintln(w, "No data to display for this report.")
+		return
+	}
+
+	widths := make([]int, len(t.Columns))
+	for c, col := range t.Columns {
+		widths[c] = displayWidth(col)
+	}
+	rows := make([][]string, len(t.Rows))
+	for r, row := range t.Rows {
+		rows[r] = humanCells(row)
+		for c, cell := range rows[r] {
+			if c < len(widths) && displayWidth(cell) > widths[c] {
+				widths[c] = displayWidth(cell)
+			}
+		}
+	}
+
+	// Text columns are left-aligned, value columns right-aligned
+	leftAligned := make([]bool, len(t.Columns))
+	for c := range leftAligned {
+		leftAligned[c] = c == 0
+		for _, row := range t.Rows {
+			if c < len(row) {
+				switch row[c].(type) {
+				case string, time.Time:
+					leftAligned[c] = true
+				}
+				break
+			}
+		}
+	}
+
+	writeLine := func(cells []string) {
+		var sb strings.Builder
+		for c, cell := range cells {
+			if c >= len(widths) {
+				break
+			}
+			if c > 0 {
+				sb.WriteString("  ")
+			}
+			pad := strings.Repeat(" ", widths[c]-displayWidth(cell))
+			if leftAligned[c] {
+				sb.WriteString(cell + pad)
+			} else {
+				sb.WriteString(pad + cell)
+			}
+		}
+		fmt.Fprintln(w, strings.TrimRight(sb.String(), " "))
+	}
+
+	writeLine(t.Columns)
+	for _, row := range rows {
+		writeLine(row)
+	}
+}
+
+// displayWidth is the number of runes in s, not counting ANSI escape sequences
+func displayWidth(s string) int {
+	width := 0
+	for i := 0; i < len(s); {
+		if s[i] == '\x1b' {
+			if end := strings.IndexByte(s[i:], 'm'); end >= 0 {
+				i += end + 1
+				continue
+			}
+		}
+		_, size := utf8.DecodeRuneInString(s[i:])
+		width++
+		i += size
+	}
+	return width
+}
+
+func humanCells(row []any) []string {
+	cells := make([]string, len(row))
+	for c, cell := range row {
+		switch v := cell.(type) {
+		case time.Duration:
+			if v == 0 {
+				cells[c] = "-"
+			} else {
+				cells[c] = FormatDuration(v)
+			}
+		case time.Time:
+			cells[c] = v.Format("2006-01-02 15:04:05")
+		case float64:
+			cells[c] = fmt.Sprintf("%.2f", v)
+		case nil:
+			cells[c] = "n/a"
+		default:
+			cells[c] = fmt.Sprint(v)
+		}
+	}
+	return cells
+}
+
+func machineCell(cell any) any {
+	switch v := cell.(type) {
+	case time.Duration:
+		return int64(v.Round(time.Second) / time.Second)
+	case SignedDuration:
+		return int64(time.Duration(v).Round(time.Second) / time.Second)
+	case time.Time:
+		return v.Format(time.RFC3339)
+	default:
+		return v
+	}
+}
+
+func escapeMarkdownCells(cells []string) []string {
+	escaped := make([]string, len(cells))
+	for i, cell := range cells {
+		escaped[i] = strings.ReplaceAll(cell, "|", "\\|")
+	}
+	return escaped
+}
@@ -0,0 +1,470 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Pauses are recorded like idle periods, the reason is stored as the window title
+const (
+	PauseStartEventType = "pause_start"
+	PauseEndEventType   = "pause_end"
+
+	pauseMetaKey       = "pause"
+	pauseCheckInterval = time.Second
+	// autoPausePrefix marks the reasons of automatic pauses in the database
+	autoPausePrefix = "auto: "
+)
+
+// PauseManager pauses and resumes the tracking, on request or automatically (see
+// AutoPauser). Every stretch of a pause with one reason is recorded as a pause_start
+// and pause_end event. The pause is kept in the meta table so that it survives a
+// restart, and a requested pause ends by itself when it was given an end time.
+type PauseManager struct {
+	mu sync.Mutex
+	// The pause requested with "pause", until "resume" or its end time
+	manual       bool
+	manualSince  time.Time
+	until        time.Time
+	manualReason string
+	// auto is the reason of the automatic pause, empty if none applies, and overridden
+	// an automatic reason tracking was resumed from, ignored until the reason changes
+	auto       string
+	overridden string
+	// recorded is the reason of the pause_start event not followed by a pause_end yet
+	recording     bool
+	recorded      string
+	recordedSince time.Time
+
+	// pending holds the events and the state to save of changes not written out yet,
+	// see flush. pendingSave is nil when the saved pause is to be deleted.
+	pending     []LogEntry
+	pendingSave *savedPause
+	savePending bool
+	flushMu     sync.Mutex
+
+	db      *Database
+	logChan chan<- LogEntry
+	// onResume is called after a pause ends, see DebouncedActivityLogger.Resumed
+	onResume func(at time.Time)
+}
+
+// savedPause is the state kept in the meta table
+type savedPause struct {
+	Manual bool       `json:"manual"`
+	Since  time.Time  `json:"since"`
+	Until  *time.Time `json:"until,omitempty"`
+	Reason string     `json:"reason,omitempty"`
+	// Recorded is the reason of an open pause_start event
+	Recorded      *string   `json:"recorded,omitempty"`
+	RecordedSince time.Time `json:"recorded_since"`
+}
+
+// NewPauseManager returns a manager saving its state to db, which it does not close
+func NewPauseManager(db *Database, logChan chan<- LogEntry) *PauseManager {
+	return &PauseManager{db: db, logChan: logChan}
+}
+
+// Paused reports whether tracking is paused; a nil manager never is
+func (p *PauseManager) Paused() bool {
+	if p == nil {
+		return false
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.recording
+}
+
+// State returns the current pause
+func (p *PauseManager) State() PauseResult {
+	if p == nil {
+		return PauseResult{}
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.state()
+}
+
+func (p *PauseManager) state() PauseResult {
+	if !p.recording {
+		return PauseResult{}
+	}
+	since := p.recordedSince
+	state := PauseResult{Paused: true, Since: &since, Reason: p.manualReason}
+	if !p.manual {
+		state.Auto, state.Reason = true, p.auto
+	} else if !p.until.IsZero() {
+		until := p.until
+		state.Until = &until
+	}
+	return state
+}
+
+// OnResume sets the function called whenever a pause ends
+func (p *PauseManager) OnResume(fn func(at time.Time)) {
+	p.mu.Lock()
+	p.onResume = fn
+	p.mu.Unlock()
+}
+
+// Pause pauses the tracking until the given time, indefinitely if it is zero. A pause
+// already requested is extended or shortened instead.
+func (p *PauseManager) Pause(until time.Time, reason string) (PauseResult, error) {
+	now := time.Now()
+	if !until.IsZero() && !until.After(now) {
+		return PauseResult{}, fmt.Errorf("pause end %s is in the past", until.Format(time.RFC3339))
+	}
+
+	p.mu.Lock()
+	if p.manual {
+		p.until = until
+		if reason != "" {
+			p.manualReason = reason
+		}
+	} else {
+		p.manual, p.manualSince, p.until, p.manualReason = true, now, until, reason
+	}
+	return p.apply(now), nil
+}
+
+// Resume ends the pause, including an automatic one until its reason changes
+func (p *PauseManager) Resume() PauseResult {
+	now := time.Now()
+	p.mu.Lock()
+	p.resume()
+	return p.apply(now)
+}
+
+// resume clears the pause, called with the lock held
+func (p *PauseManager) resume() {
+	p.manual, p.until, p.manualReason = false, time.Time{}, ""
+	p.overridden = p.auto
+}
+
+// SetAuto starts or ends the automatic pause, reason is empty when none applies
+func (p *PauseManager) SetAuto(reason string, at time.Time) {
+	p.mu.Lock()
+	if reason == p.auto {
+		p.mu.Unlock()
+		return
+	}
+	if reason != p.overridden {
+		p.overridden = ""
+	}
+	p.auto = reason
+	p.apply(at)
+}
+
+// Toggle pauses the tracking indefinitely, or resumes it
+func (p *PauseManager) Toggle() (PauseResult, error) {
+	now := time.Now()
+	p.mu.Lock()
+	if p.recording {
+		p.resume()
+	} else {
+		p.manual, p.manualSince, p.until, p.manualReason = true, now, time.Time{}, ""
+	}
+	return p.apply(now), nil
+}
+
+// apply records the pause or resume following a change and releases the lock, then
+// writes out the change. It returns the pause after the change.
+func (p *PauseManager) apply(at time.Time) PauseResult {
+	paused := p.manual || (p.auto != "" && p.auto != p.overridden)
+	reason := p.manualReason
+	if !p.manual {
+		reason = autoPausePrefix + p.auto
+	}
+	if p.recording == paused && (!paused || reason == p.recorded) {
+		state := p.state()
+		p.mu.Unlock()
+		return state
+	}
+
+	if p.recording {
+		p.pending = append(p.pending, LogEntry{Timestamp: at, EventType: PauseEndEventType})
+		p.recording = false
+	}
+	if paused {
+		entry := LogEntry{Timestamp: at, EventType: PauseStartEventType, IsIdle: true}
+		entry.EventData.Title = reason
+		p.pending = append(p.pending, entry)
+		p.recording, p.recorded, p.recordedSince = true, reason, at
+	}
+	p.pendingSave, p.savePending = p.saved(), true
+	state := p.state()
+	onResume := p.onResume
+	p.mu.Unlock()
+
+	p.flush()
+	if paused {
+		log.Printf("Activity tracking %s", state.Describe())
+	} else {
+		log.Println("Activity tracking resumed")
+		if onResume != nil {
+			onResume(at)
+		}
+	}
+	updatePauseMenu(state)
+	return state
+}
+
+// saved returns the pause to keep in the meta table, nil if there is none, called
+// with the lock held
+func (p *PauseManager) saved() *savedPause {
+	if !p.manual && !p.recording {
+		return nil
+	}
+	saved := &savedPause{Manual: p.manual, Since: p.manualSince, Reason: p.manualReason, RecordedSince: p.recordedSince}
+	if !p.until.IsZero() {
+		until := p.until
+		saved.Until = &until
+	}
+	if p.recording {
+		recorded := p.recorded
+		saved.Recorded = &recorded
+	}
+	return saved
+}
+
+// flush sends the pending events and saves the latest state without holding the
+// lock, so that a busy pipeline or database does not block the readers of the state.
+// Changes are written out in the order they were made, by whichever call gets here
+// first.
+func (p *PauseManager) flush() {
+	p.flushMu.Lock()
+	defer p.flushMu.Unlock()
+
+	p.mu.Lock()
+	entries, saved, save := p.pending, p.pendingSave, p.savePending
+	p.pending, p.pendingSave, p.savePending = nil, nil, false
+	p.mu.Unlock()
+
+	for _, entry := range entries {
+		p.logChan <- entry
+	}
+	if save {
+		p.save(saved)
+	}
+}
+
+// save keeps the pause in the meta table, or deletes it if saved is nil
+func (p *PauseManager) save(saved *savedPause) {
+	var err error
+	if saved == nil {
+		err = p.db.DeleteMeta(pauseMetaKey)
+	} else {
+		data, jsonErr := json.Marshal(saved)
+		if err = jsonErr; err == nil {
+			err = p.db.SetMeta(pauseMetaKey, string(data))
+		}
+	}
+	if err != nil {
+		log.Printf("Error saving the pause state: %v", err)
+	}
+}
+
+// Restore picks up the pause saved before the daemon restarted. A requested pause that
+// expired in the meantime ends at its end time, an automatic one is ended or continued
+// once the AutoPauser has looked at the current state.
+func (p *PauseManager) Restore(now time.Time) error {
+	value, err := p.db.GetMeta(pauseMetaKey)
+	if err != nil || value == "" {
+		return err
+	}
+
+	var saved savedPause
+	if err := json.Unmarshal([]byte(value), &saved); err != nil {
+		return fmt.Errorf("invalid saved pause: %v", err)
+	}
+
+	p.mu.Lock()
+	p.manual, p.manualSince, p.manualReason = saved.Manual, saved.Since, saved.Reason
+	if saved.Until != nil {
+		p.until = *saved.Until
+	}
+	if saved.Recorded != nil {
+		p.recording, p.recorded, p.recordedSince = true, *saved.Recorded, saved.RecordedSince
+		// Until the AutoPauser says otherwise, an automatic pause goes on
+		if !p.manual {
+			p.auto = strings.TrimPrefix(p.recorded, autoPausePrefix)
+		}
+	}
+	state := p.state()
+	p.mu.Unlock()
+
+	if state.Paused {
+		log.Printf("- Tracking %s", state.Describe())
+		updatePauseMenu(state)
+	}
+	p.expire(now)
+	return nil
+}
+
+// expire ends a requested pause once its end time has passed
+func (p *PauseManager) expire(now time.Time) {
+	p.mu.Lock()
+	if !p.manual || p.until.IsZero() || now.Before(p.until) {
+		p.mu.Unlock()
+		return
+	}
+	at := p.until
+	p.manual, p.until, p.manualReason = false, time.Time{}, ""
+	p.apply(at)
+}
+
+// Run resumes the tracking when a requested pause ends, until the context is canceled
+func (p *PauseManager) Run(ctx context.Context, wg *sync.WaitGroup) {
+	defer wg.Done()
+
+	ticker := time.NewTicker(pauseCheckInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			p.expire(now)
+		}
+	}
+}
+
+// Describe returns the pause in words, e.g. "paused until 13:00 (lunch)"
+func (r PauseResult) Describe() string {
+	if !r.Paused {
+		return "active"
+	}
+	description := "paused"
+	if r.Auto {
+		description = "paused automatically"
+	}
+	if r.Until != nil {
+		layout := "15:04"
+		if BucketStart(*r.Until, BucketDay).After(BucketStart(time.Now(), BucketDay)) {
+			layout = "Mon 15:04"
+		}
+		description += " until " + r.Until.Local().Format(layout)
+	}
+	if r.Reason != "" {
+		description += " (" + r.Reason + ")"
+	}
+	return description
+}
+
+// ResolvePauseEnd returns the end of a pause given as a duration or a time, e.g. "30m"
+// or "13:00". A time of day that has already passed today refers to tomorrow.
+func ResolvePauseEnd(length, until string, now time.Time) (time.Time, error) {
+	if length != "" && until != "" {
+		return time.Time{}, fmt.Errorf("a pause takes either a duration or an end time, not both")
+	}
+	if length != "" {
+		d, err := time.ParseDuration(length)
+		if err != nil || d <= 0 {
+			return time.Time{}, fmt.Errorf("invalid pause duration %q (expected a duration such as \"30m\")", length)
+		}
+		return now.Add(d), nil
+	}
+	if until == "" {
+		return time.Time{}, nil
+	}
+
+	end, err := ParseTimeExpression(until, now)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if !end.After(now) && len(strings.Fields(until)) == 1 && !strings.Contains(until, "T") {
+		end = end.AddDate(0, 0, 1)
+	}
+	if !end.After(now) {
+		return time.Time{}, fmt.Errorf("pause end %q is in the past", until)
+	}
+	return end, nil
+}
+
+func (d *Database) GetMeta(key string) (string, error) {
+	var value string
+	err := d.db.QueryRow("SELECT value FROM meta WHERE key = ?", key).Scan(&value)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to read %s from meta: %v", key, err)
+	}
+	return value, nil
+}
+
+func (d *Database) SetMeta(key, value string) error {
+	if _, err := d.db.Exec("INSERT INTO meta (key, value) VALUES (?, ?) ON CONFLICT(key) DO UPDATE SET value = excluded.value", key, value); err != nil {
+		return fmt.Errorf("failed to write %s to meta: %v", key, err)
+	}
+	return nil
+}
+
+func (d *Database) DeleteMeta(key string) error {
+	if _, err := d.db.Exec("DELETE FROM meta WHERE key = ?", key); err != nil {
+		return fmt.Errorf("failed to delete %s from meta: %v", key, err)
+	}
+	return nil
+}
+
+func pauseCommand(rawArgs json.RawMessage, logChan chan<- LogEntry) (any, error) {
+	if pauseManager == nil {
+		return nil, protocolErrorf(ErrUnavailable, "Pausing is unavailable")
+	}
+	var args PauseArgs
+	if err := decodeArgs(rawArgs, &args); err != nil {
+		return nil, err
+	}
+	until, err := ResolvePauseEnd(args.For, args.Until, time.Now())
+	if err != nil {
+		return nil, protocolErrorf(ErrInvalidArgument, "%v", err)
+	}
+	state, err := pauseManager.Pause(until, strings.TrimSpace(args.Reason))
+	if err != nil {
+		return nil, protocolErrorf(ErrInvalidArgument, "%v", err)
+	}
+	return state, nil
+}
+
+func resumeCommand(rawArgs json.RawMessage, logChan chan<- LogEntry) (any, error) {
+	if pauseManager == nil {
+		return nil, protocolErrorf(ErrUnavailable, "Pausing is unavailable")
+	}
+	return pauseManager.Resume(), nil
+}
+
+func runPauseCommand(args []string) error {
+	fs := flag.NewFlagSet("hyprtracker pause", flag.ExitOnError)
+	length := fs.String("for", "", "Resume automatically after this long, e.g. \"30m\"")
+	until := fs.String("until", "", "Resume automatically at this time, e.g. \"13:00\" or \"2026-10-19 09:00\"")
+	reason := fs.String("reason", "", "Why tracking is paused, e.g. \"lunch\"")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	var state PauseResult
+	if err := sendCommand("pause", PauseArgs{For: *length, Until: *until, Reason: *reason}, &state); err != nil {
+		return err
+	}
+	fmt.Printf("Tracking %s\n", state.Describe())
+	return nil
+}
+
+func runResumeCommand(args []string) error {
+	fs := flag.NewFlagSet("hyprtracker resume", flag.ExitOnError)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if err := sendCommand("resume", nil, nil); err != nil {
+		return err
+	}
+	fmt.Println("Tracking resumed")
+	return nil
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"time"
+)
+
+// PivotTable holds per-row durations split into time buckets
+type PivotTable struct {
+	Unit         BucketUnit
+	Buckets      []time.Time
+	Rows         []string
+	Cells        map[string]map[time.Time]time.Duration
+	RowTotals    map[string]time.Duration
+	BucketTotals map[time.Time]time.Duration
+	Total        time.Duration
+}
+
+// BuildPivot buckets the intervals by unit and groups them by the key returned by keyFn.
+// Rows whose total is below minDuration are dropped from the table and its totals.
+func BuildPivot(intervals []Interval, unit BucketUnit, keyFn func(Interval) string, minDuration time.Duration) *PivotTable {
+	pieces := SplitIntervalsByBucket(intervals, unit)
+
+	cells := make(map[string]map[time.Time]time.Duration)
+	rowTotals := make(map[string]time.Duration)
+	for _, p := range pieces {
+		key := keyFn(p.Interval)
+		if cells[key] == nil {
+			cells[key] = make(map[time.Time]time.Duration)
+		}
+		cells[key][p.Bucket] += p.Duration()
+		rowTotals[key] += p.Duration()
+	}
+
+	pivot := &PivotTable{
+		Unit:         unit,
+		Cells:        make(map[string]map[time.Time]time.Duration),
+		RowTotals:    make(map[string]time.Duration),
+		BucketTotals: make(map[time.Time]time.Duration),
+	}
+
+	var first, last time.Time
+	for key, total := range rowTotals {
+		if total < minDuration {
+			continue
+		}
+		pivot.Rows = append(pivot.Rows, key)
+		pivot.Cells[key] = cells[key]
+		pivot.RowTotals[key] = total
+		pivot.Total += total
+		for bucket, d := range cells[key] {
+			pivot.BucketTotals[bucket] += d
+			if first.IsZero() || bucket.Before(first) {
+				first = bucket
+			}
+			if last.IsZero() || bucket.After(last) {
+				last = bucket
+			}
+		}
+	}
+
+	sort.Slice(pivot.Rows, func(i, j int) bool {
+		a, b := pivot.Rows[i], pivot.Rows[j]
+		if pivot.RowTotals[a] != pivot.RowTotals[b] {
+			return pivot.RowTotals[a] > pivot.RowTotals[b]
+		}
+		return a < b
+	})
+
+	if !first.IsZero() {
+		pivot.Buckets = BucketRange(first, last, unit)
+	}
+
+	return pivot
+}
+
+// Table converts the pivot into a ReportTable with a total column and a total row
+func (p *PivotTable) Table(title, rowHeader string) *ReportTable {
+	table := &ReportTable{Title: title}
+	table.Columns = append(table.Columns, rowHeader)
+	for _, bucket := range p.Buckets {
+		table.Columns = append(table.Columns, BucketLabel(bucket, p.Unit))
+	}
+	table.Columns = append(table.Columns, "Total")
+
+	for _, key := range p.Rows {
+		row := []any{key}
+		for _, bucket := range p.Buckets {
+			row = append(row, p.Cells[key][bucket])
+		}
+		row = append(row, p.RowTotals[key])
+		table.AddRow(row...)
+	}
+
+	if len(p.Rows) > 0 {
+		row := []any{"Total"}
+		for _, bucket := range p.Buckets {
+			row = append(row, p.BucketTotals[bucket])
+		}
+		row = append(row, p.Total)
+		table.AddRow(row...)
+	}
+
+	return table
+}
+
+func generatePivotReport(db *Database, startTime, endTime time.Time, config AnalysisConfig) {
+	intervals := loadIntervals(db, startTime, endTime, config)
+	pivot := BuildPivot(intervals, config.Bucket, config.Config.GroupKeyFunc(config.GroupBy), config.TotalMinDuration())
+
+	rowHeader := groupByHeaders[config.GroupBy]
+	title := fmt.Sprintf("Time Spent Per %s (by %s)", rowHeader, config.Bucket)
+	if err := RenderTables(os.Stdout, config.Format, pivot.Table(title, rowHeader)); err != nil {
+		log.Fatalf("Error writing report: %v", err)
+	}
+}
@@ -0,0 +1,466 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/thiagokokada/hyprland-go/event"
+)
+
+// Focus session events: the start stores the category as the window name and the
+// planned length as the title, a distraction stores the window switched to, and the
+// end stores "completed" or "stopped" as the title
+const (
+	FocusStartEventType       = "focus_start"
+	FocusDistractionEventType = "focus_distraction"
+	FocusEndEventType         = "focus_end"
+
+	DefaultFocusLength         = 25 * time.Minute
+	DefaultFocusBreak          = 5 * time.Minute
+	DefaultFocusLongBreak      = 15 * time.Minute
+	DefaultFocusLongBreakEvery = 4
+)
+
+// FocusSessionConfig holds the defaults of focus sessions started from the tray
+type FocusSessionConfig struct {
+	// Length of a session, "25m" by default
+	Length string `json:"length"`
+	// Category the sessions are bound to, switching to other categories is a distraction
+	Category string `json:"category"`
+	// Break after a completed session, and the long break after every LongBreakEvery sessions
+	Break          string `json:"break"`
+	LongBreak      string `json:"long_break"`
+	LongBreakEvery int    `json:"long_break_every"`
+
+	length    time.Duration
+	breakLen  time.Duration
+	longBreak time.Duration
+}
+
+// validate parses the durations, leaving the defaults to the accessors
+func (f *FocusSessionConfig) validate() error {
+	var err error
+	if f.length, err = parseConfigDuration("focus_sessions length", f.Length, 0); err != nil {
+		return err
+	}
+	if f.breakLen, err = parseConfigDuration("focus_sessions break", f.Break, 0); err != nil {
+		return err
+	}
+	if f.longBreak, err = parseConfigDuration("focus_sessions long_break", f.LongBreak, 0); err != nil {
+		return err
+	}
+	if f.LongBreakEvery < 0 {
+		return fmt.Errorf("invalid focus_sessions long_break_every %d", f.LongBreakEvery)
+	}
+	return nil
+}
+
+// SessionLength is the length of sessions started without one
+func (f FocusSessionConfig) SessionLength() time.Duration {
+	if f.length <= 0 {
+		return DefaultFocusLength
+	}
+	return f.length
+}
+
+// BreakAfter returns the break following the given number of completed sessions
+func (f FocusSessionConfig) BreakAfter(completed int) time.Duration {
+	every := f.LongBreakEvery
+	if every == 0 {
+		every = DefaultFocusLongBreakEvery
+	}
+	if completed%every == 0 {
+		if f.longBreak <= 0 {
+			return DefaultFocusLongBreak
+		}
+		return f.longBreak
+	}
+	if f.breakLen <= 0 {
+		return DefaultFocusBreak
+	}
+	return f.breakLen
+}
+
+func isFocusSessionEvent(eventType string) bool {
+	return strings.HasPrefix(eventType, "focus_")
+}
+
+// FocusSession is a focus session, running or as reconstructed from its events
+type FocusSession struct {
+	Start        time.Time
+	Length       time.Duration
+	Category     string
+	Distractions int
+	// Ended is when the session completed or was stopped, zero while it is running
+	Ended  time.Time
+	Status string
+
+	// distracted is set while a window outside the category is focused
+	distracted bool
+}
+
+// PlannedEnd is when the session completes unless stopped
+func (s FocusSession) PlannedEnd() time.Time {
+	return s.Start.Add(s.Length)
+}
+
+// Describe returns the session length and category, e.g. "25m 0s on code"
+func (s FocusSession) Describe() string {
+	if s.Category == "" {
+		return FormatDuration(s.Length)
+	}
+	return FormatDuration(s.Length) + " on " + s.Category
+}
+
+// FocusSessionManager runs the focus sessions of the daemon, started and stopped from
+// the socket or the tray menu
+type FocusSessionManager struct {
+	config   *Config
+	logChan  chan<- LogEntry
+	notifier *Notifier
+
+	mu      sync.Mutex
+	current *FocusSession
+	// completed counts the sessions completed since the daemon started, for long breaks
+	completed int
+	breakEnd  time.Time
+	// The focused window, to tell whether a session starts on a distraction
+	lastApp, lastTitle string
+}
+
+func NewFocusSessionManager(config *Config, logChan chan<- LogEntry, notifier *Notifier) *FocusSessionManager {
+	return &FocusSessionManager{config: config, logChan: logChan, notifier: notifier}
+}
+
+func (m *FocusSessionManager) record(entry LogEntry) {
+	m.logChan <- entry
+}
+
+// Start begins a session of the given length, or the configured one if zero, bound to
+// category, or the configured one if empty
+func (m *FocusSessionManager) Start(length time.Duration, category string) (FocusSession, error) {
+	if length <= 0 {
+		length = m.config.FocusSessions.SessionLength()
+	}
+	if category == "" {
+		category = m.config.FocusSessions.Category
+	}
+	if category != "" && !m.config.HasCategory(category) {
+		return FocusSession{}, fmt.Errorf("unknown category %q", category)
+	}
+
+	m.mu.Lock()
+	if m.current != nil {
+		left := time.Until(m.current.PlannedEnd())
+		m.mu.Unlock()
+		return FocusSession{}, fmt.Errorf("a focus session is already running (%s left)", FormatDuration(left))
+	}
+	session := &FocusSession{Start: time.Now(), Length: length, Category: category}
+	session.distracted = category != "" && m.lastApp != "" && m.config.Categorize(m.lastApp, m.lastTitle) != category
+	m.current = session
+	m.breakEnd = time.Time{}
+	started := *session
+	m.mu.Unlock()
+
+	entry := LogEntry{Timestamp: started.Start, EventType: FocusStartEventType}
+	entry.EventData.Name = category
+	entry.EventData.Title = length.String()
+	m.record(entry)
+
+	log.Printf("Focus session started: %s", started.Describe())
+	m.notifier.Notify("focus", "Focus session started", started.Describe())
+	m.updateMenu(time.Now())
+	return started, nil
+}
+
+// Stop ends the running session early
+func (m *FocusSessionManager) Stop() (FocusSession, error) {
+	m.mu.Lock()
+	session := m.current
+	m.current = nil
+	m.mu.Unlock()
+
+	if session == nil {
+		return FocusSession{}, fmt.Errorf("no focus session is running")
+	}
+	m.finish(session, "stopped", time.Now())
+	return *session, nil
+}
+
+// Toggle stops the running session or starts one with the configured defaults
+func (m *FocusSessionManager) Toggle() {
+	if m == nil {
+		return
+	}
+	var err error
+	if _, running := m.Current(); running {
+		_, err = m.Stop()
+	} else {
+		_, err = m.Start(0, "")
+	}
+	if err != nil {
+		log.Printf("Error toggling focus session: %v", err)
+	}
+}
+
+// Current returns a copy of the running session
+func (m *FocusSessionManager) Current() (FocusSession, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.current == nil {
+		return FocusSession{}, false
+	}
+	return *m.current, true
+}
+
+func (m *FocusSessionManager) finish(session *FocusSession, status string, at time.Time) {
+	session.Ended = at
+	session.Status = status
+
+	entry := LogEntry{Timestamp: at, EventType: FocusEndEventType}
+	entry.EventData.Name = session.Category
+	entry.EventData.Title = status
+	m.record(entry)
+
+	body := fmt.Sprintf("%s, %d distractions", FormatDuration(at.Sub(session.Start)), session.Distractions)
+	log.Printf("Focus session %s: %s", status, body)
+	if status == "completed" {
+		m.mu.Lock()
+		m.completed++
+		breakLength := m.config.FocusSessions.BreakAfter(m.completed)
+		m.breakEnd = at.Add(breakLength)
+		m.mu.Unlock()
+		body += fmt.Sprintf(". Take a %s break.", FormatDuration(breakLength))
+		m.notifier.Notify("focus", "Focus session complete", body)
+	} else {
+		m.notifier.Notify("focus", "Focus session stopped", body)
+	}
+	m.updateMenu(at)
+}
+
+// observe counts the switches to windows outside the session's category
+func (m *FocusSessionManager) observe(entry LogEntry) {
+	if entry.EventType != string(event.EventActiveWindow) {
+		return
+	}
+	app, title := entry.EventData.Name, entry.EventData.Title
+
+	m.mu.Lock()
+	m.lastApp, m.lastTitle = app, title
+	session := m.current
+	if session == nil || session.Category == "" {
+		m.mu.Unlock()
+		return
+	}
+	category := m.config.Categorize(app, title)
+	distraction := category != session.Category && !session.distracted
+	session.distracted = category != session.Category
+	if distraction {
+		session.Distractions++
+	}
+	count := session.Distractions
+	m.mu.Unlock()
+
+	if !distraction {
+		return
+	}
+	distractionEntry := LogEntry{Timestamp: entry.Timestamp, EventType: FocusDistractionEventType, EventData: entry.EventData}
+	m.record(distractionEntry)
+	m.notifier.Notify("focus-distraction", "Distraction: "+app,
+		fmt.Sprintf("%s is %s, not %s (%d this session)", app, category, session.Category, count))
+	m.updateMenu(entry.Timestamp)
+}
+
+// tick completes the session once its time is up and announces the end of the break
+func (m *FocusSessionManager) tick(now time.Time) {
+	m.mu.Lock()
+	session := m.current
+	if session != nil && !now.Before(session.PlannedEnd()) {
+		m.current = nil
+		m.mu.Unlock()
+		m.finish(session, "completed", session.PlannedEnd())
+		return
+	}
+	breakOver := session == nil && !m.breakEnd.IsZero() && !now.Before(m.breakEnd)
+	if breakOver {
+		m.breakEnd = time.Time{}
+	}
+	m.mu.Unlock()
+
+	if breakOver {
+		m.notifier.Notify("focus", "Break is over", "Start the next focus session from the tray or with -focus.")
+	}
+	m.updateMenu(now)
+}
+
+// updateMenu shows the countdown of the running session in the tray menu
+func (m *FocusSessionManager) updateMenu(now time.Time) {
+	session, running := m.Current()
+	if !running {
+		updateFocusMenu(fmt.Sprintf("Start Focus Session (%s)", FormatDuration(m.config.FocusSessions.SessionLength())))
+		return
+	}
+	left := session.PlannedEnd().Sub(now)
+	if left < 0 {
+		left = 0
+	}
+	label := fmt.Sprintf("Stop Focus Session (%s left", FormatDuration(left.Truncate(time.Second)))
+	if session.Category != "" {
+		label += fmt.Sprintf(", %d distractions", session.Distractions)
+	}
+	updateFocusMenu(label + ")")
+}
+
+// Run follows the activity stream and the clock until the context is canceled
+func (m *FocusSessionManager) Run(ctx context.Context, wg *sync.WaitGroup, hub *ActivityHub) {
+	defer wg.Done()
+
+	entries := hub.Subscribe(100)
+	defer hub.Unsubscribe(entries)
+
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+	m.updateMenu(time.Now())
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case entry := <-entries:
+			m.observe(entry)
+		case now := <-ticker.C:
+			m.tick(now)
+		}
+	}
+}
+
+// BuildFocusSessions reconstructs the sessions from their events. Sessions without an
+// end event were cut short by a restart of the daemon, unless they are still running.
+func BuildFocusSessions(entries []LogEntry, now time.Time) []FocusSession {
+	var sessions []FocusSession
+	var current *FocusSession
+
+	interrupt := func(at time.Time) {
+		if current == nil {
+			return
+		}
+		current.Ended = minTime(current.PlannedEnd(), at)
+		current.Status = "interrupted"
+		sessions = append(sessions, *current)
+		current = nil
+	}
+
+	for _, entry := range entries {
+		switch entry.EventType {
+		case FocusStartEventType:
+			interrupt(entry.Timestamp)
+			length, err := time.ParseDuration(entry.EventData.Title)
+			if err != nil {
+				continue
+			}
+			current = &FocusSession{Start: entry.Timestamp, Length: length, Category: entry.EventData.Name}
+		case FocusDistractionEventType:
+			if current != nil {
+				current.Distractions++
+			}
+		case FocusEndEventType:
+			if current != nil {
+				current.Ended = entry.Timestamp
+				current.Status = entry.EventData.Title
+				sessions = append(sessions, *current)
+				current = nil
+			}
+		}
+	}
+	if current != nil {
+		if now.Before(current.PlannedEnd()) {
+			current.Status = "running"
+			sessions = append(sessions, *current)
+		} else {
+			interrupt(now)
+		}
+	}
+	return sessions
+}
+
+func generateFocusSessionsReport(db *Database, startTime, endTime time.Time, config AnalysisConfig) {
+	entries, err := db.GetEvents(startTime, endTime)
+	if err != nil {
+		log.Fatalf("Error retrieving events from database: %v", err)
+	}
+	now := time.Now()
+	sessions := BuildFocusSessions(entries, now)
+
+	sessionsTable := &ReportTable{Title: "Focus Sessions", Columns: []string{"Start", "Planned", "Focused", "Category", "Distractions", "Status"}}
+	summaryTable := &ReportTable{Title: "Focus Session Summary", Columns: []string{"Category", "Sessions", "Completed", "Completed %", "Focused", "Distractions", "Distractions/h"}}
+
+	type summary struct {
+		sessions, completed, distractions int
+		focused                           time.Duration
+	}
+	summaries := make(map[string]*summary)
+	var categories []string
+	for _, s := range sessions {
+		end := s.Ended
+		if s.Status == "running" {
+			end = now
+		}
+		category := s.Category
+		if category == "" {
+			category = "-"
+		}
+		sessionsTable.AddRow(s.Start.Local().Format("2006-01-02 15:04"), s.Length, end.Sub(s.Start), category, s.Distractions, s.Status)
+
+		sum, ok := summaries[category]
+		if !ok {
+			sum = &summary{}
+			summaries[category] = sum
+			categories = append(categories, category)
+		}
+		sum.sessions++
+		sum.distractions += s.Distractions
+		sum.focused += end.Sub(s.Start)
+		if s.Status == "completed" {
+			sum.completed++
+		}
+	}
+	sort.Strings(categories)
+	for _, category := range categories {
+		sum := summaries[category]
+		perHour := 0.0
+		if sum.focused > 0 {
+			perHour = float64(sum.distractions) / sum.focused.Hours()
+		}
+		summaryTable.AddRow(category, sum.sessions, sum.completed, float64(sum.completed)/float64(sum.sessions)*100, sum.focused, sum.distractions, perHour)
+	}
+
+	distractionsTable := &ReportTable{Title: "Top Distractions", Columns: []string{"Application", "Distractions"}}
+	counts := make(map[string]int)
+	for _, entry := range entries {
+		if entry.EventType == FocusDistractionEventType {
+			counts[entry.EventData.Name]++
+		}
+	}
+	apps := make([]string, 0, len(counts))
+	for app := range counts {
+		apps = append(apps, app)
+	}
+	sort.Slice(apps, func(i, j int) bool {
+		if counts[apps[i]] != counts[apps[j]] {
+			return counts[apps[i]] > counts[apps[j]]
+		}
+		return apps[i] < apps[j]
+	})
+	for _, app := range apps {
+		distractionsTable.AddRow(app, counts[app])
+	}
+
+	if err := RenderTables(os.Stdout, config.Format, sessionsTable, summaryTable, distractionsTable); err != nil {
+		log.Fatalf("Error writing report: %v", err)
+	}
+}
@@ -0,0 +1,393 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+	"time"
+)
+
+// The control socket speaks newline-delimited JSON: every line sent by a client is a
+// Request and every line sent back a Response carrying the same ID. A session starts
+// with a "hello" request announcing the client's protocol version, after which any
+// number of requests can be sent over the same connection. After a "subscribe" request
+// the daemon also writes a StreamEvent line for every state change, which carries no ID.
+// Plain-text commands from older clients ("idle start", "pause-toggle", ...) are still
+// accepted, one per connection.
+const (
+	ProtocolVersion  = 1
+	HelloCommand     = "hello"
+	SubscribeCommand = "subscribe"
+
+	maxMessageSize   = 64 * 1024
+	socketTimeout    = 5 * time.Second
+	sessionIdleLimit = 5 * time.Minute
+)
+
+// Error codes of failed requests
+const (
+	ErrBadRequest         = "bad_request"
+	ErrHandshakeRequired  = "handshake_required"
+	ErrUnsupportedVersion = "unsupported_version"
+	ErrUnknownCommand     = "unknown_command"
+	ErrInvalidArgument    = "invalid_argument"
+	ErrUnavailable        = "unavailable"
+	ErrFailed             = "failed"
+)
+
+type Request struct {
+	ID      int64           `json:"id"`
+	Command string          `json:"command"`
+	Args    json.RawMessage `json:"args,omitempty"`
+}
+
+type Response struct {
+	ID     int64           `json:"id"`
+	OK     bool            `json:"ok"`
+	Result json.RawMessage `json:"result,omitempty"`
+	Error  *ProtocolError  `json:"error,omitempty"`
+}
+
+// ProtocolError is the structured error of a failed request
+type ProtocolError struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *ProtocolError) Error() string {
+	return e.Message
+}
+
+func protocolErrorf(code, format string, args ...any) *ProtocolError {
+	return &ProtocolError{Code: code, Message: fmt.Sprintf(format, args...)}
+}
+
+// Arguments and results of the commands
+type (
+	HelloArgs struct {
+		Version int    `json:"version"`
+		Client  string `json:"client,omitempty"`
+	}
+	HelloResult struct {
+		Version int    `json:"version"`
+		Server  string `json:"server"`
+	}
+	// AwayArgs are the arguments of "idle", "lock" and "suspend"
+	AwayArgs struct {
+		Action string `json:"action"`
+		// Time is an RFC 3339 timestamp, the time the request is received if empty
+		Time string `json:"time,omitempty"`
+	}
+	TagArgs struct {
+		Tag string `json:"tag"`
+	}
+	PauseArgs struct {
+		// For is how long to pause, e.g. "30m", and Until the time to resume at, e.g.
+		// "13:00"; without either the pause lasts until it is resumed
+		For    string `json:"for,omitempty"`
+		Until  string `json:"until,omitempty"`
+		Reason string `json:"reason,omitempty"`
+	}
+	PauseResult struct {
+		Paused bool       `json:"paused"`
+		Since  *time.Time `json:"since,omitempty"`
+		Until  *time.Time `json:"until,omitempty"`
+		Reason string     `json:"reason,omitempty"`
+		// Auto is set when tracking was paused by the schedule or a rule
+		Auto bool `json:"auto,omitempty"`
+	}
+	FocusArgs struct {
+		// Action is "start" or "stop"
+		Action   string `json:"action"`
+		Length   string `json:"length,omitempty"`
+		Category string `json:"category,omitempty"`
+	}
+	FocusResult struct {
+		Start        time.Time `json:"start"`
+		Length       string    `json:"length"`
+		Category     string    `json:"category,omitempty"`
+		Distractions int       `json:"distractions"`
+	}
+	OverrideArgs struct {
+		// Duration is how long to suspend enforcement, the configured default if empty,
+		// or "off" to lift the override
+		Duration string `json:"duration,omitempty"`
+	}
+	OverrideResult struct {
+		Until *time.Time `json:"until"`
+	}
+	ShutdownResult struct {
+		Pid int `json:"pid"`
+	}
+	StatusResult struct {
+		Paused      bool       `json:"paused"`
+		PausedUntil *time.Time `json:"paused_until,omitempty"`
+		PauseReason string     `json:"pause_reason,omitempty"`
+		PauseAuto   bool       `json:"pause_auto,omitempty"`
+		Idle        bool       `json:"idle"`
+		// Away lists the open idle, lock and suspend periods
+		Away          []string   `json:"away,omitempty"`
+		AwaySince     *time.Time `json:"away_since,omitempty"`
+		Pid           int        `json:"pid"`
+		Started       time.Time  `json:"started"`
+		UptimeSeconds int64      `json:"uptime_seconds"`
+		App           string     `json:"app,omitempty"`
+		Title         string     `json:"title,omitempty"`
+		FocusedSince  *time.Time `json:"focused_since,omitempty"`
+		// ElapsedSeconds is how long the current window has been focused
+		ElapsedSeconds int64       `json:"elapsed_seconds"`
+		Tag            string      `json:"tag,omitempty"`
+		ActiveSeconds  int64       `json:"active_today_seconds"`
+		Events         EventCounts `json:"events"`
+		DBPath         string      `json:"db_path"`
+		LastError      string      `json:"last_error,omitempty"`
+		LastErrorAt    *time.Time  `json:"last_error_at,omitempty"`
+	}
+	EventCounts struct {
+		Received  int64 `json:"received"`
+		Debounced int64 `json:"debounced"`
+		Written   int64 `json:"written"`
+		Pending   int64 `json:"pending"`
+	}
+	SummaryArgs struct {
+		// Range is "today" (the default), one of the -time-range values or YYYY-MM-DD..YYYY-MM-DD
+		Range string `json:"range,omitempty"`
+		// Limit is the number of applications returned, all of them if zero
+		Limit int `json:"limit,omitempty"`
+	}
+	SummaryResult struct {
+		From         time.Time    `json:"from"`
+		To           time.Time    `json:"to"`
+		Description  string       `json:"description"`
+		TotalSeconds int64        `json:"total_seconds"`
+		Apps         []AppSummary `json:"apps"`
+	}
+	AppSummary struct {
+		App     string `json:"app"`
+		Seconds int64  `json:"seconds"`
+	}
+	SubscribeArgs struct {
+		// Events lists the events to stream, all of them if empty
+		Events []string `json:"events,omitempty"`
+	}
+	SubscribeResult struct {
+		Events []string `json:"events"`
+	}
+)
+
+// StreamEvent is a state change streamed to subscribed clients
+type StreamEvent struct {
+	// Event is "focus", "idle", "pause", "tag" or "goal"
+	Event string    `json:"event"`
+	Time  time.Time `json:"time"`
+	// App and Title are the newly focused window
+	App   string `json:"app,omitempty"`
+	Title string `json:"title,omitempty"`
+	// Kind is "idle", "lock" or "suspend" for idle events
+	Kind string `json:"kind,omitempty"`
+	// Action is "start" or "end" for idle and pause events
+	Action string `json:"action,omitempty"`
+	// Reason is why tracking was paused
+	Reason string `json:"reason,omitempty"`
+	// Tag is the new tag, empty when it was cleared
+	Tag  string     `json:"tag,omitempty"`
+	Goal *GoalAlert `json:"goal,omitempty"`
+	// Missed counts the events dropped before this one because the client read too slowly
+	Missed int `json:"missed,omitempty"`
+}
+
+// GoalAlert is a goal approaching or exceeding its limit, or reaching its target
+type GoalAlert struct {
+	Name          string `json:"name"`
+	Status        string `json:"status"`
+	Period        string `json:"period"`
+	SpentSeconds  int64  `json:"spent_seconds"`
+	TargetSeconds int64  `json:"target_seconds"`
+}
+
+// parseTextCommand translates a plain-text command into a request
+func parseTextCommand(message string) (Request, error) {
+	parts := strings.SplitN(message, " ", 2)
+	command := parts[0]
+	var rest string
+	if len(parts) > 1 {
+		rest = strings.TrimSpace(parts[1])
+	}
+
+	var args any
+	switch command {
+	case "idle", "lock", "suspend":
+		if rest == "" {
+			return Request{}, protocolErrorf(ErrBadRequest, "Invalid command format")
+		}
+		action, timestamp, _ := strings.Cut(rest, " ")
+		args = AwayArgs{Action: action, Time: timestamp}
+	case "tag":
+		args = TagArgs{Tag: rest}
+	case "focus":
+		fields := strings.SplitN(rest, " ", 3)
+		focus := FocusArgs{Action: fields[0]}
+		if len(fields) > 1 {
+			focus.Length = fields[1]
+		}
+		if len(fields) > 2 {
+			focus.Category = strings.TrimSpace(fields[2])
+		}
+		args = focus
+	case "override":
+		args = OverrideArgs{Duration: rest}
+	case "pause":
+		if rest != "" {
+			args = PauseArgs{For: rest}
+		}
+	}
+
+	request := Request{Command: command}
+	if args != nil {
+		data, err := json.Marshal(args)
+		if err != nil {
+			return Request{}, err
+		}
+		request.Args = data
+	}
+	return request, nil
+}
+
+// ControlClient is a session with the daemon over the control socket
+type ControlClient struct {
+	conn    net.Conn
+	reader  *bufio.Reader
+	nextID  int64
+	Version int
+	// events holds streamed events read while waiting for a response
+	events [][]byte
+}
+
+// DialControl connects to the daemon and performs the version handshake
+func DialControl(socketPath string) (*ControlClient, error) {
+	if _, err := os.Stat(socketPath); os.IsNotExist(err) {
+		// A socket-activated daemon listens on the shared socket instead
+		shared := SharedSocketPath()
+		if _, err := os.Stat(shared); err != nil || shared == socketPath {
+			return nil, fmt.Errorf("socket not found at %s - is the daemon running?", socketPath)
+		}
+		socketPath = shared
+	}
+
+	conn, err := net.Dial("unix", socketPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to socket: %v", err)
+	}
+	c := &ControlClient{conn: conn, reader: bufio.NewReaderSize(conn, 4096)}
+
+	var hello HelloResult
+	if err := c.Call(HelloCommand, HelloArgs{Version: ProtocolVersion, Client: "hyprtracker"}, &hello); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("handshake failed: %v", err)
+	}
+	c.Version = hello.Version
+	return c, nil
+}
+
+func (c *ControlClient) Close() error {
+	return c.conn.Close()
+}
+
+// Call sends a request and decodes its result into result, unless it is nil. A failed
+// request returns a *ProtocolError.
+func (c *ControlClient) Call(command string, args any, result any) error {
+	c.nextID++
+	request := Request{ID: c.nextID, Command: command}
+	if args != nil {
+		data, err := json.Marshal(args)
+		if err != nil {
+			return fmt.Errorf("failed to encode %s arguments: %v", command, err)
+		}
+		request.Args = data
+	}
+	line, err := json.Marshal(request)
+	if err != nil {
+		return fmt.Errorf("failed to encode %s request: %v", command, err)
+	}
+
+	if err := c.conn.SetDeadline(time.Now().Add(socketTimeout)); err != nil {
+		return fmt.Errorf("error setting deadline: %v", err)
+	}
+	if _, err := c.conn.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("error sending command: %v", err)
+	}
+
+	// Responses of the same session arrive in order, skip any left from earlier requests
+	for {
+		data, err := c.reader.ReadBytes('\n')
+		if err != nil {
+			return fmt.Errorf("error reading response: %v", err)
+		}
+		var response Response
+		if err := json.Unmarshal(data, &response); err != nil {
+			return fmt.Errorf("invalid response from daemon: %v", err)
+		}
+		if isStreamedEvent(data) {
+			c.events = append(c.events, data)
+			continue
+		}
+		if response.ID != request.ID {
+			continue
+		}
+		if !response.OK {
+			if response.Error == nil {
+				return protocolErrorf(ErrFailed, "%s failed", command)
+			}
+			return response.Error
+		}
+		if result != nil && len(response.Result) > 0 {
+			if err := json.Unmarshal(response.Result, result); err != nil {
+				return fmt.Errorf("invalid %s result from daemon: %v", command, err)
+			}
+		}
+		return nil
+	}
+}
+
+// Subscribe asks the daemon to stream the given events, all of them if empty
+func (c *ControlClient) Subscribe(events []string) error {
+	return c.Call(SubscribeCommand, SubscribeArgs{Events: events}, nil)
+}
+
+// NextEvent waits for the next streamed event of a subscribed session
+func (c *ControlClient) NextEvent() (StreamEvent, error) {
+	var data []byte
+	for data == nil {
+		if len(c.events) > 0 {
+			data, c.events = c.events[0], c.events[1:]
+			break
+		}
+		if err := c.conn.SetReadDeadline(time.Time{}); err != nil {
+			return StreamEvent{}, fmt.Errorf("error setting deadline: %v", err)
+		}
+		line, err := c.reader.ReadBytes('\n')
+		if err != nil {
+			return StreamEvent{}, fmt.Errorf("error reading event: %v", err)
+		}
+		if isStreamedEvent(line) {
+			data = line
+		}
+	}
+
+	var e StreamEvent
+	if err := json.Unmarshal(data, &e); err != nil {
+		return StreamEvent{}, fmt.Errorf("invalid event from daemon: %v", err)
+	}
+	return e, nil
+}
+
+// isStreamedEvent tells a streamed event from a response
+func isStreamedEvent(data []byte) bool {
+	var probe struct {
+		Event string `json:"event"`
+	}
+	return json.Unmarshal(data, &probe) == nil && probe.Event != ""
+}
@@ -0,0 +1,316 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/thiagokokada/hyprland-go/event"
+)
+
+const (
+	DefaultSearchLimit = 50
+	// Tokens of context shown around a match in a window title
+	searchSnippetTokens = 16
+
+	// The index is a regular FTS5 table keyed by events.id, so that rows which
+	// are missing from it can be found and backfilled cheaply
+	createSearchIndexSQL = `
+		CREATE VIRTUAL TABLE IF NOT EXISTS events_fts USING fts5(
+			window_name,
+			window_title,
+			tokenize = 'unicode61 remove_diacritics 2'
+		)
+	`
+	insertSearchIndexSQL = `INSERT INTO events_fts (rowid, window_name, window_title) VALUES (?, ?, ?)`
+)
+
+// SearchMatch is a focus interval whose application or title matched a search.
+// App and Title contain the highlight markers around the matching terms.
+type SearchMatch struct {
+	Start time.Time
+	End   time.Time
+	App   string
+	Title string
+}
+
+// EnableSearch creates the full-text index if needed and indexes the focus events
+// inserted from now on. It is only done by the daemon and the search command, since
+// creating the index writes to the database.
+func (d *Database) EnableSearch() error {
+	enabled, err := ensureSearchIndex(d.db)
+	if err != nil || !enabled {
+		return err
+	}
+	if d.searchStmt, err = d.db.Prepare(insertSearchIndexSQL); err != nil {
+		return fmt.Errorf("failed to prepare search index statement: %v", err)
+	}
+	d.searchEnabled = true
+	return nil
+}
+
+// ensureSearchIndex creates the full-text index, reporting false when SQLite was
+// built without FTS5 (go-sqlite3 needs the sqlite_fts5 build tag)
+func ensureSearchIndex(db *sql.DB) (bool, error) {
+	if _, err := db.Exec(createSearchIndexSQL); err != nil {
+		if strings.Contains(err.Error(), "no such module: fts5") {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to create search index: %v", err)
+	}
+	return true, nil
+}
+
+// isSearchIndexed reports whether events of this type are added to the search index
+func isSearchIndexed(entry LogEntry) bool {
+	return entry.EventType == string(event.EventActiveWindow)
+}
+
+// BackfillSearchIndex indexes the focus events that are not in the search index yet,
+// e.g. those recorded before the index existed or by a build without FTS5
+func (d *Database) BackfillSearchIndex() (int64, error) {
+	if !d.searchEnabled {
+		return 0, nil
+	}
+	result, err := d.db.Exec(`
+		INSERT INTO events_fts (rowid, window_name, window_title)
+		SELECT id, window_name, window_title
+		FROM events e
+		WHERE event_type = ?
+		AND NOT EXISTS (SELECT 1 FROM events_fts f WHERE f.rowid = e.id)
+	`, string(event.EventActiveWindow))
+	if err != nil {
+		return 0, fmt.Errorf("failed to backfill search index: %v", err)
+	}
+	return result.RowsAffected()
+}
+
+// SearchEvents returns the focus intervals between startTime and endTime whose
+// application or title match the query, newest first. With FTS5 the query uses its
+// syntax (prefix* and "exact phrase" queries); without it every term has to appear
+// as a substring. Matching terms are wrapped in before and after.
+func (d *Database) SearchEvents(query string, startTime, endTime time.Time, limit int, before, after string) ([]SearchMatch, error) {
+	// The interval of a focus event lasts until the next event in time, which is not
+	// always the next one recorded since idle markers may be backdated. Like in
+	// BuildIntervals, tags, break reminders and focus sessions don't end it.
+	const selectSQL = `
+		SELECT e.timestamp,
+			(SELECT n.timestamp FROM events n
+				WHERE n.timestamp >= e.timestamp AND (n.timestamp > e.timestamp OR n.id > e.id)
+				AND n.event_type != '` + TagEventType + `'
+				AND n.event_type NOT LIKE 'break\_%' ESCAPE '\'
+				AND n.event_type NOT LIKE 'focus\_%' ESCAPE '\'
+				ORDER BY n.timestamp, n.id LIMIT 1),
+	`
+
+	var rows *sql.Rows
+	var err error
+	var terms []string
+	if d.searchEnabled {
+		rows, err = d.db.Query(selectSQL+`
+				highlight(events_fts, 0, ?, ?),
+				snippet(events_fts, 1, ?, ?, '…', ?)
+			FROM events_fts
+			JOIN events e ON e.id = events_fts.rowid
+			WHERE events_fts MATCH ?
+			AND e.timestamp BETWEEN ? AND ?
+			ORDER BY e.timestamp DESC
+			LIMIT ?
+		`, before, after, before, after, searchSnippetTokens, query,
+			startTime.Format(time.RFC3339), endTime.Format(time.RFC3339), limit)
+		if err != nil {
+			return nil, fmt.Errorf("invalid search query %q: %v", query, err)
+		}
+	} else {
+		terms = parseSearchTerms(query)
+		if len(terms) == 0 {
+			return nil, fmt.Errorf("invalid search query %q", query)
+		}
+		conditions := make([]string, len(terms))
+		args := []any{string(event.EventActiveWindow), startTime.Format(time.RFC3339), endTime.Format(time.RFC3339)}
+		for i, term := range terms {
+			conditions[i] = "(LOWER(e.window_name) LIKE ? OR LOWER(e.window_title) LIKE ?)"
+			pattern := "%" + strings.ToLower(term) + "%"
+			args = append(args, pattern, pattern)
+		}
+		args = append(args, limit)
+		rows, err = d.db.Query(selectSQL+`
+				e.window_name,
+				e.window_title
+			FROM events e
+			WHERE e.event_type = ?
+			AND e.timestamp BETWEEN ? AND ?
+			AND `+strings.Join(conditions, " AND ")+`
+			ORDER BY e.timestamp DESC
+			LIMIT ?
+		`, args...)
+		if err != nil {
+			return nil, fmt.Errorf("query failed: %v", err)
+		}
+	}
+	defer rows.Close()
+
+	var matches []SearchMatch
+	for rows.Next() {
+		var startStr string
+		var endStr sql.NullString
+		var match SearchMatch
+		if err := rows.Scan(&startStr, &endStr, &match.App, &match.Title); err != nil {
+			return nil, fmt.Errorf("row scan failed: %v", err)
+		}
+		if match.Start, err = time.Parse(time.RFC3339, startStr); err != nil {
+			return nil, fmt.Errorf("timestamp parse failed: %v", err)
+		}
+		// The most recent event has no end yet
+		match.End = match.Start
+		if endStr.Valid {
+			if match.End, err = time.Parse(time.RFC3339, endStr.String); err != nil {
+				return nil, fmt.Errorf("timestamp parse failed: %v", err)
+			}
+		}
+		if terms != nil {
+			match.App = highlightTerms(match.App, terms, before, after)
+			match.Title = highlightTerms(match.Title, terms, before, after)
+		}
+
+		// Repeated events for the same window are shown as a single match
+		if n := len(matches); n > 0 {
+			newer := &matches[n-1]
+			if newer.App == match.App && newer.Title == match.Title && newer.Start.Equal(match.End) {
+				newer.Start = match.Start
+				continue
+			}
+		}
+		matches = append(matches, match)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("invalid search query %q: %v", query, err)
+	}
+
+	return matches, nil
+}
+
+// parseSearchTerms splits a query into terms for substring matching, keeping
+// "quoted phrases" together and dropping FTS5 prefix stars and AND operators
+func parseSearchTerms(query string) []string {
+	var terms []string
+	for i, part := range strings.Split(query, `"`) {
+		if i%2 == 1 {
+			if phrase := strings.TrimSpace(part); phrase != "" {
+				terms = append(terms, phrase)
+			}
+			continue
+		}
+		for _, word := range strings.Fields(part) {
+			word = strings.TrimSuffix(word, "*")
+			if word != "" && word != "AND" {
+				terms = append(terms, word)
+			}
+		}
+	}
+	return terms
+}
+
+// highlightTerms wraps every case-insensitive occurrence of the terms in s
+func highlightTerms(s string, terms []string, before, after string) string {
+	lower := strings.ToLower(s)
+	if len(lower) != len(s) {
+		// Lowercasing changed the byte offsets, matches cannot be mapped back
+		return s
+	}
+	marked := make([]bool, len(s))
+	for _, term := range terms {
+		term = strings.ToLower(term)
+		for from := 0; ; {
+			i := strings.Index(lower[from:], term)
+			if i < 0 {
+				break
+			}
+			for j := from + i; j < from+i+len(term); j++ {
+				marked[j] = true
+			}
+			from += i + len(term)
+		}
+	}
+
+	var sb strings.Builder
+	for i := 0; i < len(s); i++ {
+		if marked[i] && (i == 0 || !marked[i-1]) {
+			sb.WriteString(before)
+		}
+		sb.WriteByte(s[i])
+		if marked[i] && (i == len(s)-1 || !marked[i+1]) {
+			sb.WriteString(after)
+		}
+	}
+	return sb.String()
+}
+
+// searchMarkers returns the strings placed around matching terms for a format
+func searchMarkers(format string) (string, string) {
+	switch {
+	case format == FormatMarkdown:
+		return "**", "**"
+	case format == FormatText && UseColor():
+		return "\x1b[1;33m", ansiReset
+	default:
+		return "[", "]"
+	}
+}
+
+// runSearchCommand prints the focus intervals within the time range matching the query
+func runSearchCommand(args []string) error {
+	fs, dbPath := newCommandFlags("search")
+	timeRange := fs.String("time-range", "month", "Time range: 'day', 'week', 'month', 'year', 'all' or YYYY-MM-DD..YYYY-MM-DD")
+	limit := fs.Int("limit", DefaultSearchLimit, "Maximum number of matches shown")
+	format := fs.String("format", FormatText, "Output format: 'text', 'csv', 'markdown' or 'json'")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if err := ValidateOutputFormat(*format); err != nil {
+		return err
+	}
+	query := strings.Join(fs.Args(), " ")
+	if strings.TrimSpace(query) == "" {
+		return fmt.Errorf("missing search query")
+	}
+
+	db, err := OpenDatabase(*dbPath)
+	if err != nil {
+		return fmt.Errorf("error opening database: %v", err)
+	}
+	defer db.Close()
+
+	if err := db.EnableSearch(); err != nil {
+		return err
+	}
+	if db.searchEnabled {
+		if _, err := db.BackfillSearchIndex(); err != nil {
+			return err
+		}
+	} else {
+		log.Println("Full-text search is not available in this build (build with -tags sqlite_fts5), falling back to substring matching")
+	}
+
+	startTime, endTime, description, err := ResolveAnalysisRange(*timeRange, time.Now())
+	if err != nil {
+		return err
+	}
+	before, after := searchMarkers(*format)
+	matches, err := db.SearchEvents(query, startTime, endTime, *limit, before, after)
+	if err != nil {
+		return err
+	}
+
+	table := &ReportTable{
+		Title:   fmt.Sprintf("Matches for %s in %s", query, description),
+		Columns: []string{"Start", "End", "Duration", "Application", "Title"},
+	}
+	for _, m := range matches {
+		table.AddRow(m.Start.Local(), m.End.Local(), m.End.Sub(m.Start), m.App, m.Title)
+	}
+	return RenderTables(os.Stdout, *format, table)
+}
@@ -0,0 +1,173 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"time"
+)
+
+const DefaultBreakThreshold = 15 * time.Minute
+
+// WorkSession is a stretch of activity without a break longer than the threshold
+type WorkSession struct {
+	Start  time.Time
+	End    time.Time
+	Active time.Duration
+}
+
+// WorkDay summarizes the sessions that started on one local day
+type WorkDay struct {
+	Day      time.Time
+	Sessions []WorkSession
+}
+
+func (d WorkDay) FirstActivity() time.Time {
+	return d.Sessions[0].Start
+}
+
+func (d WorkDay) LastActivity() time.Time {
+	return d.Sessions[len(d.Sessions)-1].End
+}
+
+func (d WorkDay) Active() time.Duration {
+	var active time.Duration
+	for _, s := range d.Sessions {
+		active += s.Active
+	}
+	return active
+}
+
+// Breaks is the time between clocking in and out that was not spent active
+func (d WorkDay) Breaks() time.Duration {
+	return d.LastActivity().Sub(d.FirstActivity()) - d.Active()
+}
+
+// BuildSessions groups focus intervals into work sessions. A new session starts when
+// the gap between two intervals (which includes any idle time) exceeds breakThreshold,
+// or when the screen was locked or the machine suspended in between.
+func BuildSessions(intervals []Interval, away []AwayPeriod, breakThreshold time.Duration) []WorkSession {
+	sorted := make([]Interval, len(intervals))
+	copy(sorted, intervals)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].Start.Before(sorted[j].Start)
+	})
+
+	var sessions []WorkSession
+	for _, iv := range sorted {
+		if len(sessions) > 0 {
+			last := &sessions[len(sessions)-1]
+			gap := iv.Start.Sub(last.End)
+			if gap <= breakThreshold && !leftBetween(away, last.End, iv.Start) {
+				if iv.End.After(last.End) {
+					last.End = iv.End
+				}
+				last.Active += iv.Duration()
+				continue
+			}
+		}
+		sessions = append(sessions, WorkSession{Start: iv.Start, End: iv.End, Active: iv.Duration()})
+	}
+
+	return sessions
+}
+
+// leftBetween reports whether a lock or suspend period overlaps [from, to]
+func leftBetween(away []AwayPeriod, from, to time.Time) bool {
+	for _, p := range away {
+		if p.Kind == "idle" {
+			continue
+		}
+		if !p.Start.After(to) && !p.End.Before(from) {
+			return true
+		}
+	}
+	return false
+}
+
+// PausedPerDay sums the time tracking was paused per local day
+func PausedPerDay(away []AwayPeriod) map[time.Time]time.Duration {
+	paused := make(map[time.Time]time.Duration)
+	for _, p := range away {
+		if p.Kind != "pause" {
+			continue
+		}
+		for start := p.Start; start.Before(p.End); {
+			day := BucketStart(start, BucketDay)
+			end := minTime(p.End, NextBucket(day, BucketDay))
+			paused[day] += end.Sub(start)
+			start = end
+		}
+	}
+	return paused
+}
+
+// GroupSessionsByDay assigns each session to the local day on which it started
+func GroupSessionsByDay(sessions []WorkSession) []WorkDay {
+	var days []WorkDay
+	for _, s := range sessions {
+		day := BucketStart(s.Start, BucketDay)
+		if len(days) == 0 || !days[len(days)-1].Day.Equal(day) {
+			days = append(days, WorkDay{Day: day})
+		}
+		days[len(days)-1].Sessions = append(days[len(days)-1].Sessions, s)
+	}
+	return days
+}
+
+func generateSessionsReport(db *Database, startTime, endTime time.Time, config AnalysisConfig) {
+	entries := loadEvents(db, startTime, endTime)
+	intervals := config.FilterIntervals(mergeManualEntries(db, BuildIntervals(entries, time.Time{}), startTime, endTime))
+	away := BuildAwayPeriods(entries, time.Time{})
+	sessions := BuildSessions(intervals, away, config.BreakThreshold)
+	days := GroupSessionsByDay(sessions)
+	paused := PausedPerDay(away)
+
+	daysTable := &ReportTable{
+		Title:   fmt.Sprintf("Work Days (breaks longer than %s split sessions)", FormatDuration(config.BreakThreshold)),
+		Columns: []string{"Date", "Clock In", "Clock Out", "Sessions", "Active", "Breaks", "Paused"},
+	}
+	sessionsTable := &ReportTable{
+		Title:   "Sessions",
+		Columns: []string{"Date", "Start", "End", "Active", "Break Before"},
+	}
+
+	for _, day := range days {
+		daysTable.AddRow(
+			day.Day.Format("Mon 2006-01-02"),
+			clockTime(day.FirstActivity(), day.Day),
+			clockTime(day.LastActivity(), day.Day),
+			len(day.Sessions),
+			day.Active(),
+			day.Breaks(),
+			paused[day.Day],
+		)
+		for i, s := range day.Sessions {
+			var breakBefore time.Duration
+			if i > 0 {
+				breakBefore = s.Start.Sub(day.Sessions[i-1].End)
+			}
+			sessionsTable.AddRow(
+				day.Day.Format("Mon 2006-01-02"),
+				clockTime(s.Start, day.Day),
+				clockTime(s.End, day.Day),
+				s.Active,
+				breakBefore,
+			)
+		}
+	}
+
+	if err := RenderTables(os.Stdout, config.Format, daysTable, sessionsTable); err != nil {
+		log.Fatalf("Error writing report: %v", err)
+	}
+}
+
+// clockTime formats t as a time of day, marking times that fall on a later day than day
+func clockTime(t, day time.Time) string {
+	clock := t.Local().Format("15:04")
+	if days := int(BucketStart(t, BucketDay).Sub(day).Hours()+12) / 24; days > 0 {
+		clock += fmt.Sprintf(" (+%dd)", days)
+	}
+	return clock
+}
@@ -1,14 +1,20 @@
 package main
 
 import (
+	"bufio"
+	"bytes"
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"log"
 	"net"
 	"os"
+	"path/filepath"
 	"strings"
 	"sync"
+	"syscall"
 	"time"
 )
 
@@ -17,31 +23,82 @@ type IdleMessage struct {
 	Time   time.Time
 }
 
-// creates a Unix domain socket to listen for commands (idle events, pause toggle)
-func StartSocketListener(ctx context.Context, wg *sync.WaitGroup, logChan chan<- LogEntry) error {
-	if _, err := os.Stat(SocketPath); err == nil {
-		if err := os.Remove(SocketPath); err != nil {
-			return fmt.Errorf("failed to remove existing socket: %v", err)
-		}
+// Client timestamps of idle, lock and suspend markers must lie within these bounds
+const (
+	maxTimestampAge  = 15 * time.Minute
+	maxTimestampSkew = time.Minute
+)
+
+// GetSocketPath returns the control socket in the user's runtime directory, one per
+// Hyprland instance
+func GetSocketPath() string {
+	if signature := os.Getenv("HYPRLAND_INSTANCE_SIGNATURE"); signature != "" && !strings.ContainsAny(signature, "/\x00") {
+		return filepath.Join(socketDir(), signature+".sock")
+	}
+	return SharedSocketPath()
+}
+
+// SharedSocketPath returns the control socket that is not bound to a Hyprland instance,
+// used outside of Hyprland and by a socket-activated daemon
+func SharedSocketPath() string {
+	return filepath.Join(socketDir(), "hyprtracker.sock")
+}
+
+func socketDir() string {
+	runtimeDir := os.Getenv("XDG_RUNTIME_DIR")
+	if runtimeDir == "" {
+		runtimeDir = fallbackRuntimeDir()
 	}
+	return filepath.Join(runtimeDir, "hyprtracker")
+}
 
-	listener, err := net.Listen("unix", SocketPath)
+// fallbackRuntimeDir replaces XDG_RUNTIME_DIR when it is not set
+func fallbackRuntimeDir() string {
+	return filepath.Join(os.TempDir(), fmt.Sprintf("hyprtracker-%d", os.Getuid()))
+}
+
+// checkPrivateDir fails unless path is a directory, not a symlink, that belongs to the
+// current user and is accessible only to them
+func checkPrivateDir(path string) error {
+	info, err := os.Lstat(path)
 	if err != nil {
-		return fmt.Errorf("failed to create socket: %v", err)
+		return err
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("%s is not a directory", path)
 	}
+	if stat, ok := info.Sys().(*syscall.Stat_t); !ok || int(stat.Uid) != os.Getuid() {
+		return fmt.Errorf("%s is not owned by the current user", path)
+	}
+	if info.Mode().Perm() != 0700 {
+		return fmt.Errorf("%s has mode %#o instead of 0700", path, info.Mode().Perm())
+	}
+	return nil
+}
 
-	if err := os.Chmod(SocketPath, 0666); err != nil {
-		listener.Close()
-		return fmt.Errorf("failed to set socket permissions: %v", err)
+// listens for commands (idle events, pause toggle) on the socket passed by systemd, or
+// on a Unix domain socket created at SocketPath and accessible only to the current user
+func StartSocketListener(ctx context.Context, wg *sync.WaitGroup, logChan chan<- LogEntry, activated net.Listener) error {
+	listener, address := activated, SocketPath
+	if activated != nil {
+		address = activated.Addr().String()
+	} else {
+		var err error
+		if listener, err = createControlSocket(); err != nil {
+			return err
+		}
 	}
 
 	wg.Add(1)
 	go func() {
 		defer wg.Done()
 		defer listener.Close()
-		defer os.Remove(SocketPath)
+		// The socket passed by systemd stays in place for the next start
+		if activated == nil {
+			defer os.Remove(SocketPath)
+		}
 
-		log.Printf("Command socket listener started at %s", SocketPath)
+		log.Printf("Command socket listener started at %s", address)
 
 		connChan := make(chan net.Conn)
 		errChan := make(chan error)
@@ -68,6 +125,11 @@ func StartSocketListener(ctx context.Context, wg *sync.WaitGroup, logChan chan<-
 				}
 				return
 			case conn := <-connChan:
+				if err := checkPeerCredentials(conn); err != nil {
+					log.Printf("Rejected connection: %v", err)
+					conn.Close()
+					continue
+				}
 				go handleSocketConnection(conn, logChan)
 			}
 		}
@@ -76,144 +138,516 @@ func StartSocketListener(ctx context.Context, wg *sync.WaitGroup, logChan chan<-
 	return nil
 }
 
-// processes a single connection to the command socket
+// createControlSocket creates the Unix domain socket at SocketPath
+func createControlSocket() (net.Listener, error) {
+	socketDir := filepath.Dir(SocketPath)
+	if err := os.MkdirAll(socketDir, 0700); err != nil {
+		return nil, fmt.Errorf("failed to create socket directory: %v", err)
+	}
+	// Another user may have created the fallback in the shared temp dir first
+	if os.Getenv("XDG_RUNTIME_DIR") == "" {
+		if err := checkPrivateDir(fallbackRuntimeDir()); err != nil {
+			return nil, fmt.Errorf("refusing to use the socket directory: %v", err)
+		}
+	}
+	if err := os.Chmod(socketDir, 0700); err != nil {
+		return nil, fmt.Errorf("failed to set socket directory permissions: %v", err)
+	}
+	if err := checkPrivateDir(socketDir); err != nil {
+		return nil, fmt.Errorf("refusing to use the socket directory: %v", err)
+	}
+
+	// ClaimInstance made sure that no daemon answers on a socket still there, which was
+	// left behind by one that crashed
+	if _, err := os.Stat(SocketPath); err == nil {
+		if err := os.Remove(SocketPath); err != nil {
+			return nil, fmt.Errorf("failed to remove existing socket: %v", err)
+		}
+	}
+
+	// Create the socket without group and world permissions rather than chmod it afterwards
+	oldMask := syscall.Umask(0177)
+	listener, err := net.Listen("unix", SocketPath)
+	syscall.Umask(oldMask)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create socket: %v", err)
+	}
+
+	if err := os.Chmod(SocketPath, 0600); err != nil {
+		listener.Close()
+		return nil, fmt.Errorf("failed to set socket permissions: %v", err)
+	}
+	return listener, nil
+}
+
+// checkPeerCredentials accepts only connections from processes of the same user
+func checkPeerCredentials(conn net.Conn) error {
+	unixConn, ok := conn.(*net.UnixConn)
+	if !ok {
+		return fmt.Errorf("not a unix socket connection")
+	}
+	rawConn, err := unixConn.SyscallConn()
+	if err != nil {
+		return fmt.Errorf("failed to access connection: %v", err)
+	}
+
+	var cred *syscall.Ucred
+	var credErr error
+	if err := rawConn.Control(func(fd uintptr) {
+		cred, credErr = syscall.GetsockoptUcred(int(fd), syscall.SOL_SOCKET, syscall.SO_PEERCRED)
+	}); err != nil {
+		return fmt.Errorf("failed to access connection: %v", err)
+	}
+	if credErr != nil {
+		return fmt.Errorf("failed to read peer credentials: %v", credErr)
+	}
+	if int(cred.Uid) != os.Getuid() {
+		return fmt.Errorf("peer uid %d (pid %d) is not the daemon's uid %d", cred.Uid, cred.Pid, os.Getuid())
+	}
+	return nil
+}
+
+// processes a single connection to the command socket: a JSON session, or a single
+// plain-text command from an older client
 func handleSocketConnection(conn net.Conn, logChan chan<- LogEntry) {
 	defer conn.Close()
 
-	if err := conn.SetReadDeadline(time.Now().Add(5 * time.Second)); err != nil {
+	if err := conn.SetReadDeadline(time.Now().Add(socketTimeout)); err != nil {
 		log.Printf("Error setting read deadline: %v", err)
 		return
 	}
 
-	buf := make([]byte, 128)
-	n, err := conn.Read(buf)
+	reader := bufio.NewReaderSize(conn, 4096)
+	first, err := reader.Peek(1)
 	if err != nil {
 		log.Printf("Error reading from socket: %v", err)
 		return
 	}
+	if first[0] == '{' {
+		serveSession(conn, reader, logChan)
+		return
+	}
+
+	// Text commands are sent in a single write without a trailing newline
+	data, _ := reader.Peek(reader.Buffered())
+	message := strings.TrimSpace(string(data))
+	request, err := parseTextCommand(message)
+	if err == nil {
+		_, err = dispatchCommand(request, logChan)
+	}
+	if err != nil {
+		log.Printf("Command %q failed: %v", message, err)
+		_, _ = conn.Write([]byte("ERROR: " + err.Error()))
+		return
+	}
+	_, _ = conn.Write([]byte("OK"))
+}
 
-	message := strings.TrimSpace(string(buf[:n]))
-	parts := strings.SplitN(message, " ", 2)
-	command := parts[0]
+// serveSession answers the requests of a JSON session until the client disconnects
+func serveSession(conn net.Conn, reader *bufio.Reader, logChan chan<- LogEntry) {
+	w := &sessionWriter{conn: conn}
+	handshaken := false
 
-	// Process commands
-	switch command {
-	case "idle":
-		// Handle idle commands
-		if len(parts) < 2 {
-			log.Printf("Invalid idle command format: %s", message)
-			_, _ = conn.Write([]byte("ERROR: Invalid command format"))
-			return
+	var subscription *Subscription
+	done := make(chan struct{})
+	defer func() {
+		close(done)
+		if subscription != nil {
+			eventStream.Unsubscribe(subscription)
 		}
-		
-		idleParts := strings.SplitN(parts[1], " ", 2)
-		action := idleParts[0]
-		
-		// Skip idle events processing if tracking is paused
-		if trackingPaused {
-			_, _ = conn.Write([]byte("OK"))
+	}()
+
+	for {
+		// Subscribed clients may stay silent for as long as they listen
+		deadline := time.Now().Add(sessionIdleLimit)
+		if subscription != nil {
+			deadline = time.Time{}
+		}
+		if err := conn.SetReadDeadline(deadline); err != nil {
+			log.Printf("Error setting read deadline: %v", err)
 			return
 		}
-		
-		var timestamp time.Time
-		if len(idleParts) > 1 && idleParts[1] != "" {
-			parsedTime, err := time.Parse(time.RFC3339, idleParts[1])
-			if err != nil {
-				log.Printf("Invalid timestamp format, using current time: %v", err)
-				timestamp = time.Now()
-			} else {
-				timestamp = parsedTime
+		line, err := readMessage(reader)
+		if len(bytes.TrimSpace(line)) == 0 {
+			if err != nil && err != io.EOF {
+				log.Printf("Error reading from socket: %v", err)
 			}
-		} else {
-			timestamp = time.Now()
-		}
-		
-		switch action {
-		case "start":
-			logChan <- LogEntry{
-				Timestamp: timestamp,
-				EventType: "idle_start",
-				IsIdle:    true,
+			return
+		}
+		if err == errMessageTooLarge {
+			w.writeResponse(Response{Error: protocolErrorf(ErrBadRequest, "message exceeds %d bytes", maxMessageSize)})
+			return
+		}
+
+		var request Request
+		if jsonErr := json.Unmarshal(line, &request); jsonErr != nil {
+			w.writeResponse(Response{Error: protocolErrorf(ErrBadRequest, "invalid request: %v", jsonErr)})
+		} else if request.Command == HelloCommand {
+			result, helloErr := handleHello(request.Args)
+			handshaken = handshaken || helloErr == nil
+			w.writeResponse(newResponse(request.ID, result, helloErr))
+		} else if !handshaken {
+			w.writeResponse(Response{ID: request.ID, Error: protocolErrorf(ErrHandshakeRequired, "send %q with the protocol version first", HelloCommand)})
+		} else if request.Command == SubscribeCommand {
+			var result any
+			s, subErr := subscribe(request.Args, subscription)
+			if subErr == nil {
+				subscription = s
+				result = SubscribeResult{Events: s.Events()}
 			}
-			log.Printf("Received idle start signal at %s", timestamp.Format(time.RFC3339))
-		case "end":
-			logChan <- LogEntry{
-				Timestamp: timestamp,
-				EventType: "idle_end",
-				IsIdle:    false,
+			// The response goes out before the first event
+			w.writeResponse(newResponse(request.ID, result, subErr))
+			if subErr == nil {
+				go serveSubscription(w, subscription, done)
 			}
-			log.Printf("Received idle end signal at %s", timestamp.Format(time.RFC3339))
-		default:
-			log.Printf("Unknown idle action: %s", action)
-			_, _ = conn.Write([]byte("ERROR: Unknown idle action"))
+		} else {
+			result, cmdErr := dispatchCommand(request, logChan)
+			w.writeResponse(newResponse(request.ID, result, cmdErr))
+		}
+
+		if err != nil {
 			return
 		}
-		
-	case "pause-toggle":
-		// Toggle tracking state
-		toggleTracking()
-		log.Printf("Tracking state toggled via socket command")
-		
-	default:
-		log.Printf("Unknown command: %s", command)
-		_, _ = conn.Write([]byte("ERROR: Unknown command"))
-		return
 	}
-
-	_, _ = conn.Write([]byte("OK"))
 }
 
-// sends a command to the daemon via the socket
-func sendCommand(command string) error {
-	if _, err := os.Stat(SocketPath); os.IsNotExist(err) {
-		return fmt.Errorf("socket not found at %s - is the daemon running?", SocketPath)
+var errMessageTooLarge = errors.New("message too large")
+
+// readMessage reads one newline-terminated message of at most maxMessageSize bytes
+func readMessage(reader *bufio.Reader) ([]byte, error) {
+	var line []byte
+	for {
+		chunk, err := reader.ReadSlice('\n')
+		line = append(line, chunk...)
+		if len(line) > maxMessageSize {
+			return line, errMessageTooLarge
+		}
+		if err == bufio.ErrBufferFull {
+			continue
+		}
+		return line, err
 	}
+}
 
-	conn, err := net.Dial("unix", SocketPath)
+func newResponse(id int64, result any, err error) Response {
+	response := Response{ID: id, OK: err == nil}
 	if err != nil {
-		return fmt.Errorf("failed to connect to socket: %v", err)
+		var protocolErr *ProtocolError
+		if !errors.As(err, &protocolErr) {
+			protocolErr = &ProtocolError{Code: ErrFailed, Message: err.Error()}
+		}
+		response.Error = protocolErr
+		return response
 	}
-	defer conn.Close()
+	if result != nil {
+		data, err := json.Marshal(result)
+		if err != nil {
+			return Response{ID: id, Error: protocolErrorf(ErrFailed, "failed to encode result: %v", err)}
+		}
+		response.Result = data
+	}
+	return response
+}
+
+// sessionWriter writes the lines of a session, which come from the session itself and
+// from its subscription
+type sessionWriter struct {
+	mu   sync.Mutex
+	conn net.Conn
+}
 
-	if err := conn.SetWriteDeadline(time.Now().Add(5 * time.Second)); err != nil {
+// write sends v as a single line, failing if the client does not read it in time
+func (w *sessionWriter) write(v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("failed to encode message: %v", err)
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if err := w.conn.SetWriteDeadline(time.Now().Add(socketTimeout)); err != nil {
 		return fmt.Errorf("error setting write deadline: %v", err)
 	}
+	if _, err := w.conn.Write(append(data, '\n')); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (w *sessionWriter) writeResponse(response Response) {
+	if err := w.write(response); err != nil {
+		log.Printf("Error writing response: %v", err)
+	}
+}
 
-	if _, err := conn.Write([]byte(command)); err != nil {
-		return fmt.Errorf("error sending command: %v", err)
+func handleHello(rawArgs json.RawMessage) (any, error) {
+	var args HelloArgs
+	if err := decodeArgs(rawArgs, &args); err != nil {
+		return nil, err
+	}
+	if args.Version < 1 {
+		return nil, protocolErrorf(ErrUnsupportedVersion, "protocol version %d is not supported (server speaks %d)", args.Version, ProtocolVersion)
 	}
+	// Newer clients fall back to the version spoken by the server
+	return HelloResult{Version: min(args.Version, ProtocolVersion), Server: "hyprtracker"}, nil
+}
 
-	if err := conn.SetReadDeadline(time.Now().Add(5 * time.Second)); err != nil {
-		return fmt.Errorf("error setting read deadline: %v", err)
+// decodeArgs decodes the arguments of a request, which may be omitted
+func decodeArgs(rawArgs json.RawMessage, args any) error {
+	if len(rawArgs) == 0 {
+		return nil
 	}
+	if err := json.Unmarshal(rawArgs, args); err != nil {
+		return protocolErrorf(ErrInvalidArgument, "invalid arguments: %v", err)
+	}
+	return nil
+}
 
-	buf := make([]byte, 128)
-	n, err := conn.Read(buf)
+// subscribe starts the subscription of a session, which can only subscribe once
+func subscribe(rawArgs json.RawMessage, current *Subscription) (*Subscription, error) {
+	if current != nil {
+		return nil, protocolErrorf(ErrBadRequest, "the session is already subscribed to %s", strings.Join(current.Events(), ", "))
+	}
+	var args SubscribeArgs
+	if err := decodeArgs(rawArgs, &args); err != nil {
+		return nil, err
+	}
+	subscription, err := eventStream.Subscribe(args.Events, subscriptionBuffer)
 	if err != nil {
-		return fmt.Errorf("error reading acknowledgment: %v", err)
+		return nil, protocolErrorf(ErrInvalidArgument, "%v", err)
+	}
+	return subscription, nil
+}
+
+// commandHandler runs a command with its raw arguments and returns its result
+type commandHandler func(args json.RawMessage, logChan chan<- LogEntry) (any, error)
+
+var controlCommands = map[string]commandHandler{
+	"idle":         awayCommand("idle"),
+	"lock":         awayCommand("lock"),
+	"suspend":      awayCommand("suspend"),
+	"tag":          tagCommand,
+	"pause-toggle": pauseToggleCommand,
+	"pause":        pauseCommand,
+	"resume":       resumeCommand,
+	"focus":        focusCommand,
+	"override":     overrideCommand,
+	"status":       statusCommand,
+	"summary":      summaryCommand,
+	"shutdown":     shutdownCommand,
+}
+
+func dispatchCommand(request Request, logChan chan<- LogEntry) (any, error) {
+	handler, ok := controlCommands[request.Command]
+	if !ok {
+		return nil, protocolErrorf(ErrUnknownCommand, "Unknown command: %s", request.Command)
 	}
+	return handler(request.Args, logChan)
+}
+
+// awayCommand handles idle, screen lock and suspend markers
+func awayCommand(kind string) commandHandler {
+	return func(rawArgs json.RawMessage, logChan chan<- LogEntry) (any, error) {
+		var args AwayArgs
+		if err := decodeArgs(rawArgs, &args); err != nil {
+			return nil, err
+		}
+		if args.Action != "start" && args.Action != "end" {
+			return nil, protocolErrorf(ErrInvalidArgument, "Unknown %s action: %s", kind, args.Action)
+		}
+
+		// Skip away periods starting while tracking is paused. Their end is still
+		// recorded, in case the period started before the pause.
+		if args.Action == "start" && pauseManager.Paused() {
+			return nil, nil
+		}
+
+		now := time.Now()
+		timestamp := now
+		if args.Time != "" {
+			parsedTime, err := time.Parse(time.RFC3339, args.Time)
+			if err != nil {
+				log.Printf("Invalid timestamp format, using current time: %v", err)
+			} else {
+				timestamp = parsedTime
+			}
+		}
+		if timestamp.Before(now.Add(-maxTimestampAge)) || timestamp.After(now.Add(maxTimestampSkew)) {
+			return nil, protocolErrorf(ErrInvalidArgument, "%s timestamp %s is more than %s in the past or %s in the future",
+				kind, timestamp.Format(time.RFC3339), FormatDuration(maxTimestampAge), FormatDuration(maxTimestampSkew))
+		}
 
-	response := string(buf[:n])
-	if !strings.HasPrefix(response, "OK") {
-		return fmt.Errorf("unexpected response from daemon: %s", response)
+		logChan <- LogEntry{
+			Timestamp: timestamp,
+			EventType: kind + "_" + args.Action,
+			IsIdle:    args.Action == "start",
+		}
+		log.Printf("Received %s %s signal at %s", kind, args.Action, timestamp.Format(time.RFC3339))
+		return nil, nil
 	}
+}
 
-	return nil
+// tagCommand sets the tag for the current and following activity, or clears it
+func tagCommand(rawArgs json.RawMessage, logChan chan<- LogEntry) (any, error) {
+	var args TagArgs
+	if err := decodeArgs(rawArgs, &args); err != nil {
+		return nil, err
+	}
+	tag := strings.TrimSpace(args.Tag)
+	if err := ValidateTag(tag); err != nil {
+		return nil, protocolErrorf(ErrInvalidArgument, "%v", err)
+	}
+
+	entry := LogEntry{Timestamp: time.Now(), EventType: TagEventType}
+	entry.EventData.Title = tag
+	logChan <- entry
+	setActiveTag(tag)
+	if tag == "" {
+		log.Println("Tag cleared via socket command")
+	} else {
+		log.Printf("Tag set to %q via socket command", tag)
+	}
+	return nil, nil
+}
+
+func pauseToggleCommand(rawArgs json.RawMessage, logChan chan<- LogEntry) (any, error) {
+	if pauseManager == nil {
+		return nil, protocolErrorf(ErrUnavailable, "Pausing is unavailable")
+	}
+	log.Printf("Tracking state toggled via socket command")
+	return pauseManager.Toggle()
+}
+
+// shutdownCommand asks the daemon to shut down, as a daemon started with -replace does
+func shutdownCommand(rawArgs json.RawMessage, logChan chan<- LogEntry) (any, error) {
+	select {
+	case shutdownChan <- struct{}{}:
+	default:
+	}
+	log.Println("Shutdown requested via socket command")
+	return ShutdownResult{Pid: os.Getpid()}, nil
+}
+
+// focusCommand starts or stops a focus session
+func focusCommand(rawArgs json.RawMessage, logChan chan<- LogEntry) (any, error) {
+	if focusSessions == nil {
+		return nil, protocolErrorf(ErrUnavailable, "Focus sessions are unavailable")
+	}
+	var args FocusArgs
+	if err := decodeArgs(rawArgs, &args); err != nil {
+		return nil, err
+	}
+
+	var session FocusSession
+	var err error
+	switch args.Action {
+	case "start":
+		var length time.Duration
+		if args.Length != "" {
+			if length, err = time.ParseDuration(args.Length); err != nil || length <= 0 {
+				return nil, protocolErrorf(ErrInvalidArgument, "invalid focus session length %q", args.Length)
+			}
+		}
+		session, err = focusSessions.Start(length, args.Category)
+	case "stop":
+		session, err = focusSessions.Stop()
+	default:
+		return nil, protocolErrorf(ErrInvalidArgument, "Unknown focus action: %s", args.Action)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return FocusResult{Start: session.Start, Length: session.Length.String(), Category: session.Category, Distractions: session.Distractions}, nil
+}
+
+// overrideCommand suspends the enforcement rules, or lifts the override
+func overrideCommand(rawArgs json.RawMessage, logChan chan<- LogEntry) (any, error) {
+	if enforcer == nil {
+		return nil, protocolErrorf(ErrUnavailable, "Enforcement is not configured")
+	}
+	var args OverrideArgs
+	if err := decodeArgs(rawArgs, &args); err != nil {
+		return nil, err
+	}
+
+	length := enforcer.DefaultOverride()
+	switch args.Duration {
+	case "":
+	case "off":
+		length = 0
+	default:
+		d, err := time.ParseDuration(args.Duration)
+		if err != nil || d <= 0 {
+			return nil, protocolErrorf(ErrInvalidArgument, "Invalid override duration: %s", args.Duration)
+		}
+		length = d
+	}
+
+	var result OverrideResult
+	if until := enforcer.Override(length); !until.IsZero() {
+		result.Until = &until
+	}
+	return result, nil
+}
+
+// sends a command to the daemon via the socket, decoding its result into result unless it is nil
+func sendCommand(command string, args any, result any) error {
+	client, err := DialControl(SocketPath)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+	return client.Call(command, args, result)
 }
 
 // sends an idle signal to the daemon
 func SendIdleSignal(action string) error {
+	return SendAwaySignal("idle", action)
+}
+
+// sends an idle, lock or suspend signal to the daemon
+func SendAwaySignal(kind, action string) error {
 	if action != "start" && action != "end" {
-		return fmt.Errorf("invalid idle action: %s (must be 'start' or 'end')", action)
+		return fmt.Errorf("invalid %s action: %s (must be 'start' or 'end')", kind, action)
 	}
+	return sendCommand(kind, AwayArgs{Action: action, Time: time.Now().Format(time.RFC3339)}, nil)
+}
 
-	command := fmt.Sprintf("idle %s %s", action, time.Now().Format(time.RFC3339))
-	return sendCommand(command)
+// sets the tag of the current activity on the daemon, an empty tag clears it
+func SendTagSignal(tag string) error {
+	tag = strings.TrimSpace(tag)
+	if err := ValidateTag(tag); err != nil {
+		return err
+	}
+	return sendCommand("tag", TagArgs{Tag: tag}, nil)
 }
 
-// sends a toggle-pause signal to the daemon
-func SendPauseToggleSignal() error {
-	return sendCommand("pause-toggle")
+// starts a focus session of the given length on the daemon, or stops it with "stop"
+func SendFocusSignal(length, category string) error {
+	if length == "stop" {
+		return sendCommand("focus", FocusArgs{Action: "stop"}, nil)
+	}
+	d, err := time.ParseDuration(length)
+	if err != nil || d <= 0 {
+		return fmt.Errorf("invalid focus session length %q (expected a duration such as \"25m\" or 'stop')", length)
+	}
+	return sendCommand("focus", FocusArgs{Action: "start", Length: d.String(), Category: strings.TrimSpace(category)}, nil)
 }
 
+// suspends the enforcement rules on the daemon for a duration, or lifts the override with "off"
+func SendOverrideSignal(length string) error {
+	if length != "off" {
+		d, err := time.ParseDuration(length)
+		if err != nil || d <= 0 {
+			return fmt.Errorf("invalid override duration %q (expected a duration such as \"15m\" or 'off')", length)
+		}
+		length = d.String()
+	}
+	return sendCommand("override", OverrideArgs{Duration: length}, nil)
+}
 
+// sends a toggle-pause signal to the daemon and returns whether tracking is paused now
+func SendPauseToggleSignal() (bool, error) {
+	var result PauseResult
+	err := sendCommand("pause-toggle", nil, &result)
+	return result.Paused, err
+}
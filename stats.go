@@ -0,0 +1,184 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"time"
+)
+
+// Upper bounds of the visit length histogram buckets, roughly tripling each step.
+// The last bucket is open-ended.
+var visitHistogramBounds = []time.Duration{
+	10 * time.Second,
+	30 * time.Second,
+	time.Minute,
+	3 * time.Minute,
+	10 * time.Minute,
+	30 * time.Minute,
+	time.Hour,
+	3 * time.Hour,
+}
+
+// VisitStats describes the distribution of visit lengths for one app/category
+type VisitStats struct {
+	Name      string
+	Visits    []time.Duration // sorted ascending
+	Total     time.Duration
+	Histogram []int
+}
+
+func (v VisitStats) Mean() time.Duration {
+	if len(v.Visits) == 0 {
+		return 0
+	}
+	return v.Total / time.Duration(len(v.Visits))
+}
+
+func (v VisitStats) Median() time.Duration {
+	return Percentile(v.Visits, 50)
+}
+
+func (v VisitStats) P90() time.Duration {
+	return Percentile(v.Visits, 90)
+}
+
+func (v VisitStats) Longest() time.Duration {
+	if len(v.Visits) == 0 {
+		return 0
+	}
+	return v.Visits[len(v.Visits)-1]
+}
+
+// visitHistogramBucket returns the index of the histogram bucket for a visit length
+func visitHistogramBucket(d time.Duration) int {
+	for i, bound := range visitHistogramBounds {
+		if d < bound {
+			return i
+		}
+	}
+	return len(visitHistogramBounds)
+}
+
+// visitHistogramLabels returns the column labels for the histogram buckets
+func visitHistogramLabels() []string {
+	short := func(d time.Duration) string {
+		switch {
+		case d >= time.Hour:
+			return fmt.Sprintf("%dh", d/time.Hour)
+		case d >= time.Minute:
+			return fmt.Sprintf("%dm", d/time.Minute)
+		default:
+			return fmt.Sprintf("%ds", d/time.Second)
+		}
+	}
+
+	labels := make([]string, 0, len(visitHistogramBounds)+1)
+	labels = append(labels, "<"+short(visitHistogramBounds[0]))
+	for i := 1; i < len(visitHistogramBounds); i++ {
+		labels = append(labels, short(visitHistogramBounds[i-1])+"–"+short(visitHistogramBounds[i]))
+	}
+	labels = append(labels, "≥"+short(visitHistogramBounds[len(visitHistogramBounds)-1]))
+	return labels
+}
+
+// CalculateVisitStats groups the intervals into visits (contiguous spans with the same
+// key, see BuildFocusSpans) and computes per-key statistics, longest total first
+func CalculateVisitStats(intervals []Interval, keyFn func(Interval) string) []VisitStats {
+	byKey := make(map[string]*VisitStats)
+	for _, span := range BuildFocusSpans(intervals, keyFn) {
+		stats, ok := byKey[span.Key]
+		if !ok {
+			stats = &VisitStats{Name: span.Key, Histogram: make([]int, len(visitHistogramBounds)+1)}
+			byKey[span.Key] = stats
+		}
+		stats.Visits = append(stats.Visits, span.Duration())
+		stats.Total += span.Duration()
+		stats.Histogram[visitHistogramBucket(span.Duration())]++
+	}
+
+	result := make([]VisitStats, 0, len(byKey))
+	for _, stats := range byKey {
+		sort.Slice(stats.Visits, func(i, j int) bool { return stats.Visits[i] < stats.Visits[j] })
+		result = append(result, *stats)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Total != result[j].Total {
+			return result[i].Total > result[j].Total
+		}
+		return result[i].Name < result[j].Name
+	})
+	return result
+}
+
+// FilterShortVisits drops every interval belonging to a visit to an app shorter than minDuration
+func FilterShortVisits(intervals []Interval, minDuration time.Duration) []Interval {
+	if minDuration <= 0 {
+		return intervals
+	}
+
+	var kept []Interval
+	runStart := 0
+	var runDuration time.Duration
+	flush := func(end int) {
+		if runDuration >= minDuration {
+			kept = append(kept, intervals[runStart:end]...)
+		}
+	}
+
+	for i, iv := range intervals {
+		if i > 0 {
+			prev := intervals[i-1]
+			if prev.App != iv.App || iv.Start.After(prev.End) {
+				flush(i)
+				runStart, runDuration = i, 0
+			}
+		}
+		runDuration += iv.Duration()
+	}
+	flush(len(intervals))
+
+	return kept
+}
+
+func generateStatsReport(db *Database, startTime, endTime time.Time, config AnalysisConfig) {
+	intervals := loadIntervals(db, startTime, endTime, config)
+	stats := CalculateVisitStats(intervals, config.Config.GroupKeyFunc(config.GroupBy))
+	header := groupByHeaders[config.GroupBy]
+
+	summary := &ReportTable{
+		Title:   "Visit Statistics Per " + header,
+		Columns: []string{header, "Visits", "Total", "Mean", "Median", "P90", "Longest"},
+	}
+	histogram := &ReportTable{
+		Title:   "Visit Length Histogram (number of visits)",
+		Columns: append([]string{header}, visitHistogramLabels()...),
+	}
+	overall := make([]int, len(visitHistogramBounds)+1)
+
+	for _, s := range stats {
+		if s.Total < config.TotalMinDuration() {
+			continue
+		}
+		summary.AddRow(s.Name, len(s.Visits), s.Total, s.Mean(), s.Median(), s.P90(), s.Longest())
+
+		row := []any{s.Name}
+		for i, count := range s.Histogram {
+			row = append(row, count)
+			overall[i] += count
+		}
+		histogram.AddRow(row...)
+	}
+	if len(histogram.Rows) > 0 {
+		row := []any{"All"}
+		for _, count := range overall {
+			row = append(row, count)
+		}
+		histogram.AddRow(row...)
+	}
+
+	if err := RenderTables(os.Stdout, config.Format, summary, histogram); err != nil {
+		log.Fatalf("Error writing report: %v", err)
+	}
+}
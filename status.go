@@ -0,0 +1,325 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/thiagokokada/hyprland-go/event"
+)
+
+// DaemonStatus collects the live state of the daemon reported by the status command
+type DaemonStatus struct {
+	// Window events received from Hyprland, dropped by the debouncing, and rows
+	// written to the database, of which pending are not committed yet
+	received  atomic.Int64
+	debounced atomic.Int64
+	written   atomic.Int64
+	pending   atomic.Int64
+
+	// started and dbPath do not change after NewDaemonStatus
+	started time.Time
+	dbPath  string
+
+	mu          sync.Mutex
+	lastError   string
+	lastErrorAt time.Time
+
+	// The focused window since focusedSince, counted into activeToday up to countedUntil
+	app, title   string
+	focusedSince time.Time
+	countedUntil time.Time
+	activeToday  time.Duration
+	day          time.Time
+	away         map[string]bool
+	awaySince    time.Time
+
+	onChange func()
+}
+
+var daemonStatus = NewDaemonStatus("")
+
+// NewDaemonStatus returns the status of a daemon started now and writing to dbPath
+func NewDaemonStatus(dbPath string) *DaemonStatus {
+	return &DaemonStatus{started: time.Now(), dbPath: dbPath, away: make(map[string]bool)}
+}
+
+// RecordError keeps err as the last error shown by the status command
+func (s *DaemonStatus) RecordError(err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.lastError = err.Error()
+	s.lastErrorAt = time.Now()
+}
+
+// OnChange sets the function called after every entry the status has followed
+func (s *DaemonStatus) OnChange(fn func()) {
+	s.mu.Lock()
+	s.onChange = fn
+	s.mu.Unlock()
+}
+
+// seed restores today's active time and the focused window from the database
+func (s *DaemonStatus) seed(now time.Time) {
+	db, err := OpenDatabase(s.dbPath)
+	if err != nil {
+		log.Printf("Error loading today's activity for the status: %v", err)
+		return
+	}
+	defer db.Close()
+
+	day := BucketStart(now, BucketDay)
+	intervals, err := db.GetIntervals(day, now, now)
+	if err != nil {
+		log.Printf("Error loading today's activity for the status: %v", err)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.day = day
+	for _, iv := range intervals {
+		s.activeToday += iv.Duration()
+	}
+	// The window focused before a restart is still focused unless an event says otherwise
+	if n := len(intervals); n > 0 && intervals[n-1].End.Equal(now) && intervals[n-1].ManualID == 0 {
+		last := intervals[n-1]
+		s.app, s.title, s.focusedSince, s.countedUntil = last.App, last.Title, last.Start, now
+	}
+}
+
+// count adds the time the focused window has been focused to today's active time
+func (s *DaemonStatus) count(now time.Time) {
+	if day := BucketStart(now, BucketDay); !day.Equal(s.day) {
+		s.day = day
+		s.activeToday = 0
+		if !s.countedUntil.IsZero() {
+			s.countedUntil = maxTime(s.countedUntil, day)
+		}
+	}
+	if s.countedUntil.IsZero() || !now.After(s.countedUntil) {
+		return
+	}
+	s.activeToday += now.Sub(s.countedUntil)
+	s.countedUntil = now
+}
+
+func (s *DaemonStatus) observe(entry LogEntry) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.count(entry.Timestamp)
+	if kind, start, ok := awayEventKind(entry.EventType); ok {
+		if start {
+			if len(s.away) == 0 {
+				s.awaySince = entry.Timestamp
+			}
+			s.away[kind] = true
+			s.countedUntil = time.Time{}
+			return
+		}
+		delete(s.away, kind)
+		if len(s.away) == 0 && s.app != "" {
+			s.countedUntil = entry.Timestamp
+		}
+		return
+	}
+	if entry.EventType == string(event.EventActiveWindow) {
+		s.app, s.title, s.focusedSince = entry.EventData.Name, entry.EventData.Title, entry.Timestamp
+		if len(s.away) == 0 {
+			s.countedUntil = entry.Timestamp
+		}
+	}
+}
+
+// Run follows the activity stream until the context is canceled
+func (s *DaemonStatus) Run(ctx context.Context, wg *sync.WaitGroup, hub *ActivityHub) {
+	defer wg.Done()
+
+	entries := hub.Subscribe(100)
+	defer hub.Unsubscribe(entries)
+	s.seed(time.Now())
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case entry := <-entries:
+			s.observe(entry)
+			s.mu.Lock()
+			onChange := s.onChange
+			s.mu.Unlock()
+			if onChange != nil {
+				onChange()
+			}
+		}
+	}
+}
+
+// Snapshot returns the current status
+func (s *DaemonStatus) Snapshot(now time.Time) StatusResult {
+	pause := pauseManager.State()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.count(now)
+
+	status := StatusResult{
+		Paused:        pause.Paused,
+		PausedUntil:   pause.Until,
+		PauseReason:   pause.Reason,
+		PauseAuto:     pause.Auto,
+		Pid:           os.Getpid(),
+		Started:       s.started,
+		UptimeSeconds: int64(now.Sub(s.started).Seconds()),
+		Tag:           currentTag(),
+		ActiveSeconds: int64(s.activeToday.Seconds()),
+		Events: EventCounts{
+			Received:  s.received.Load(),
+			Debounced: s.debounced.Load(),
+			Written:   s.written.Load(),
+			Pending:   s.pending.Load(),
+		},
+		DBPath:    s.dbPath,
+		LastError: s.lastError,
+	}
+	// A pause stops the count like an idle period but is reported on its own
+	for kind := range s.away {
+		if kind != "pause" {
+			status.Away = append(status.Away, kind)
+		}
+	}
+	sort.Strings(status.Away)
+	if len(status.Away) > 0 {
+		status.Idle = true
+		awaySince := s.awaySince
+		status.AwaySince = &awaySince
+	}
+	if s.app != "" {
+		focusedSince := s.focusedSince
+		status.App, status.Title, status.FocusedSince = s.app, s.title, &focusedSince
+		status.ElapsedSeconds = int64(now.Sub(s.focusedSince).Seconds())
+	}
+	if !s.lastErrorAt.IsZero() {
+		lastErrorAt := s.lastErrorAt
+		status.LastErrorAt = &lastErrorAt
+	}
+	return status
+}
+
+func statusCommand(rawArgs json.RawMessage, logChan chan<- LogEntry) (any, error) {
+	return daemonStatus.Snapshot(time.Now()), nil
+}
+
+// Summary returns the time per application in the range, longest first
+func (s *DaemonStatus) Summary(timeRange string, limit int, now time.Time) (SummaryResult, error) {
+	var from, to time.Time
+	var description string
+	if timeRange == "" || timeRange == "today" {
+		from, to, description = BucketStart(now, BucketDay), now, "today"
+	} else {
+		var err error
+		if from, to, description, err = ResolveAnalysisRange(timeRange, now); err != nil {
+			return SummaryResult{}, protocolErrorf(ErrInvalidArgument, "%v", err)
+		}
+	}
+
+	db, err := OpenDatabase(s.dbPath)
+	if err != nil {
+		return SummaryResult{}, fmt.Errorf("failed to open database: %v", err)
+	}
+	defer db.Close()
+	intervals, err := db.GetIntervals(from, to, minTime(to, now))
+	if err != nil {
+		return SummaryResult{}, fmt.Errorf("failed to load activity: %v", err)
+	}
+
+	result := SummaryResult{From: from, To: to, Description: description, Apps: []AppSummary{}}
+	for _, app := range SortedSummary(groupDurations(intervals, func(iv Interval) string { return iv.App }), 0) {
+		result.TotalSeconds += int64(app.Duration.Seconds())
+		if limit <= 0 || len(result.Apps) < limit {
+			result.Apps = append(result.Apps, AppSummary{App: app.Name, Seconds: int64(app.Duration.Seconds())})
+		}
+	}
+	return result, nil
+}
+
+func summaryCommand(rawArgs json.RawMessage, logChan chan<- LogEntry) (any, error) {
+	var args SummaryArgs
+	if err := decodeArgs(rawArgs, &args); err != nil {
+		return nil, err
+	}
+	return daemonStatus.Summary(args.Range, args.Limit, time.Now())
+}
+
+// State describes whether the daemon is tracking, e.g. "active" or "idle (lock)"
+func (s StatusResult) State() string {
+	switch {
+	case s.Paused:
+		return "paused"
+	case s.Idle:
+		return "idle (" + strings.Join(s.Away, ", ") + ")"
+	default:
+		return "active"
+	}
+}
+
+// WriteStatus prints the status for humans
+func WriteStatus(s StatusResult) {
+	seconds := func(n int64) string {
+		return FormatDuration(time.Duration(n) * time.Second)
+	}
+
+	state := s.State()
+	if s.Paused {
+		state = PauseResult{Paused: true, Until: s.PausedUntil, Reason: s.PauseReason, Auto: s.PauseAuto}.Describe()
+	} else if s.AwaySince != nil {
+		state += " since " + s.AwaySince.Local().Format("15:04")
+	}
+	fmt.Printf("Status:        %s\n", state)
+	fmt.Printf("Uptime:        %s (since %s, pid %d)\n", seconds(s.UptimeSeconds), s.Started.Local().Format("2006-01-02 15:04"), s.Pid)
+	if s.App != "" {
+		fmt.Printf("Focused:       %s – %s (%s)\n", s.App, s.Title, seconds(s.ElapsedSeconds))
+	} else {
+		fmt.Printf("Focused:       unknown\n")
+	}
+	if s.Tag != "" {
+		fmt.Printf("Tag:           %s\n", s.Tag)
+	}
+	fmt.Printf("Active today:  %s\n", seconds(s.ActiveSeconds))
+	fmt.Printf("Events:        %d received, %d debounced, %d written, %d pending\n",
+		s.Events.Received, s.Events.Debounced, s.Events.Written, s.Events.Pending)
+	fmt.Printf("Database:      %s\n", s.DBPath)
+	if s.LastError == "" {
+		fmt.Printf("Last error:    none\n")
+	} else {
+		fmt.Printf("Last error:    %s (at %s)\n", s.LastError, s.LastErrorAt.Local().Format("2006-01-02 15:04:05"))
+	}
+}
+
+func runStatusCommand(args []string) error {
+	fs := flag.NewFlagSet("hyprtracker status", flag.ExitOnError)
+	jsonOutput := fs.Bool("json", false, "Print the status as JSON")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	var status StatusResult
+	if err := sendCommand("status", nil, &status); err != nil {
+		return err
+	}
+	if *jsonOutput {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(status)
+	}
+	WriteStatus(status)
+	return nil
+}
@@ -0,0 +1,211 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/thiagokokada/hyprland-go/event"
+)
+
+// Events streamed to subscribed clients
+const (
+	StreamFocus = "focus"
+	StreamIdle  = "idle"
+	StreamPause = "pause"
+	StreamTag   = "tag"
+	StreamGoal  = "goal"
+
+	// subscriptionBuffer is how many events a subscriber may fall behind before
+	// events are dropped for it
+	subscriptionBuffer = 64
+)
+
+var streamEventNames = []string{StreamFocus, StreamIdle, StreamPause, StreamTag, StreamGoal}
+
+// Subscription receives the streamed events a client subscribed to
+type Subscription struct {
+	C      chan StreamEvent
+	events map[string]bool
+	// missed counts the events dropped since the last one delivered
+	missed int
+}
+
+// EventStream passes state changes on to the subscribed clients. Every subscriber has
+// its own buffer, and events are dropped for a subscriber that falls behind so that a
+// slow client never holds up the activity pipeline or other subscribers.
+type EventStream struct {
+	mu          sync.Mutex
+	subscribers map[*Subscription]struct{}
+}
+
+func NewEventStream() *EventStream {
+	return &EventStream{subscribers: make(map[*Subscription]struct{})}
+}
+
+var eventStream = NewEventStream()
+
+// Subscribe returns a subscription to the given events, all of them if empty
+func (s *EventStream) Subscribe(events []string, size int) (*Subscription, error) {
+	sub := &Subscription{C: make(chan StreamEvent, size), events: make(map[string]bool)}
+	for _, name := range events {
+		if !isStreamEvent(name) {
+			return nil, fmt.Errorf("unknown event %q (must be one of %s)", name, strings.Join(streamEventNames, ", "))
+		}
+		sub.events[name] = true
+	}
+	if len(sub.events) == 0 {
+		for _, name := range streamEventNames {
+			sub.events[name] = true
+		}
+	}
+
+	s.mu.Lock()
+	s.subscribers[sub] = struct{}{}
+	s.mu.Unlock()
+	return sub, nil
+}
+
+func (s *EventStream) Unsubscribe(sub *Subscription) {
+	s.mu.Lock()
+	delete(s.subscribers, sub)
+	s.mu.Unlock()
+}
+
+// Events returns the names of the subscribed events
+func (sub *Subscription) Events() []string {
+	var names []string
+	for name := range sub.events {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Publish sends the event to its subscribers without waiting for any of them
+func (s *EventStream) Publish(e StreamEvent) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for sub := range s.subscribers {
+		if !sub.events[e.Event] {
+			continue
+		}
+		delivered := e
+		delivered.Missed = sub.missed
+		select {
+		case sub.C <- delivered:
+			sub.missed = 0
+		default:
+			sub.missed++
+		}
+	}
+}
+
+func isStreamEvent(name string) bool {
+	for _, n := range streamEventNames {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
+
+// streamEventFor translates a logged entry into a streamed event
+func streamEventFor(entry LogEntry) (StreamEvent, bool) {
+	e := StreamEvent{Time: entry.Timestamp}
+	if kind, start, ok := awayEventKind(entry.EventType); ok {
+		e.Event, e.Kind, e.Action = StreamIdle, kind, "end"
+		if kind == "pause" {
+			e.Event, e.Kind, e.Reason = StreamPause, "", entry.EventData.Title
+		}
+		if start {
+			e.Action = "start"
+		}
+		return e, true
+	}
+	switch entry.EventType {
+	case string(event.EventActiveWindow):
+		e.Event, e.App, e.Title = StreamFocus, entry.EventData.Name, entry.EventData.Title
+	case TagEventType:
+		e.Event, e.Tag = StreamTag, entry.EventData.Title
+	default:
+		return e, false
+	}
+	return e, true
+}
+
+// RunEventStream streams the focus changes, idle markers, pauses and tags passing
+// through the hub. The hub only sees window events that made it through the debouncing.
+func RunEventStream(ctx context.Context, wg *sync.WaitGroup, hub *ActivityHub, stream *EventStream) {
+	defer wg.Done()
+
+	entries := hub.Subscribe(100)
+	defer hub.Unsubscribe(entries)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case entry := <-entries:
+			if e, ok := streamEventFor(entry); ok {
+				stream.Publish(e)
+			}
+		}
+	}
+}
+
+// serveSubscription writes the events of sub to the session until done is closed or
+// the client stops reading, in which case the connection is closed
+func serveSubscription(w *sessionWriter, sub *Subscription, done <-chan struct{}) {
+	for {
+		select {
+		case <-done:
+			return
+		case e := <-sub.C:
+			if err := w.write(e); err != nil {
+				w.conn.Close()
+				return
+			}
+		}
+	}
+}
+
+func runSubscribeCommand(args []string) error {
+	fs := flag.NewFlagSet("hyprtracker subscribe", flag.ExitOnError)
+	events := fs.String("events", "", "Comma-separated events to stream ("+strings.Join(streamEventNames, ", ")+"), all if empty")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	var names []string
+	for _, name := range strings.Split(*events, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			names = append(names, name)
+		}
+	}
+
+	client, err := DialControl(SocketPath)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+	if err := client.Subscribe(names); err != nil {
+		return err
+	}
+
+	encoder := json.NewEncoder(os.Stdout)
+	for {
+		e, err := client.NextEvent()
+		if err != nil {
+			return err
+		}
+		if err := encoder.Encode(e); err != nil {
+			return err
+		}
+	}
+}
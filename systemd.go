@@ -0,0 +1,166 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// listenFdsStart is the first file descriptor passed by systemd socket activation
+const listenFdsStart = 3
+
+// SystemdNotifier speaks the sd_notify protocol when the daemon runs as a systemd
+// service of Type=notify: it reports readiness and the tracking state, and pings the
+// watchdog. Without NOTIFY_SOCKET, or when nil, it does nothing.
+type SystemdNotifier struct {
+	conn     *net.UnixConn
+	watchdog time.Duration
+
+	mu     sync.Mutex
+	status string
+}
+
+// NewSystemdNotifier connects to the socket systemd passed in NOTIFY_SOCKET, and takes
+// the watchdog interval from WATCHDOG_USEC. The variables are cleared so that they are
+// not inherited by the processes the daemon starts.
+func NewSystemdNotifier() (*SystemdNotifier, error) {
+	socket := os.Getenv("NOTIFY_SOCKET")
+	usec, watchdogPid := os.Getenv("WATCHDOG_USEC"), os.Getenv("WATCHDOG_PID")
+	os.Unsetenv("NOTIFY_SOCKET")
+	os.Unsetenv("WATCHDOG_USEC")
+	os.Unsetenv("WATCHDOG_PID")
+	if socket == "" {
+		return nil, nil
+	}
+
+	// An address starting with @ is in the abstract namespace, which net handles
+	conn, err := net.DialUnix("unixgram", nil, &net.UnixAddr{Name: socket, Net: "unixgram"})
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to the notify socket %s: %v", socket, err)
+	}
+	n := &SystemdNotifier{conn: conn}
+
+	if usec != "" && (watchdogPid == "" || watchdogPid == strconv.Itoa(os.Getpid())) {
+		us, err := strconv.ParseInt(usec, 10, 64)
+		if err != nil || us <= 0 {
+			conn.Close()
+			return nil, fmt.Errorf("invalid WATCHDOG_USEC %q", usec)
+		}
+		n.watchdog = time.Duration(us) * time.Microsecond
+	}
+	return n, nil
+}
+
+func (n *SystemdNotifier) notify(state string) {
+	if n == nil {
+		return
+	}
+	if _, err := n.conn.Write([]byte(state)); err != nil {
+		log.Printf("Error notifying systemd: %v", err)
+	}
+}
+
+// Ready tells systemd that the daemon has started
+func (n *SystemdNotifier) Ready() {
+	n.notify("READY=1")
+}
+
+// Stopping tells systemd that the daemon is shutting down
+func (n *SystemdNotifier) Stopping() {
+	n.notify("STOPPING=1")
+}
+
+// SetStatus shows text as the status of the service, e.g. in systemctl status
+func (n *SystemdNotifier) SetStatus(text string) {
+	if n == nil {
+		return
+	}
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if text == n.status {
+		return
+	}
+	n.status = text
+	n.notify("STATUS=" + text)
+}
+
+func (n *SystemdNotifier) Close() {
+	if n != nil {
+		n.conn.Close()
+	}
+}
+
+// systemdStatus describes the tracking state in a line
+func systemdStatus(s StatusResult) string {
+	switch {
+	case s.Paused:
+		return PauseResult{Paused: true, Until: s.PausedUntil, Reason: s.PauseReason, Auto: s.PauseAuto}.Describe()
+	case s.Idle:
+		return s.State()
+	case s.App != "":
+		return "active: " + s.App
+	default:
+		return "active"
+	}
+}
+
+// RunWatchdog pings the watchdog at half its interval for as long as the activity hub
+// takes entries, so that systemd restarts a daemon whose event processing is stuck
+func (n *SystemdNotifier) RunWatchdog(ctx context.Context, wg *sync.WaitGroup, hub *ActivityHub) {
+	defer wg.Done()
+	if n == nil || n.watchdog == 0 {
+		return
+	}
+	ticker := time.NewTicker(n.watchdog / 2)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if hub.Ping(n.watchdog / 4) {
+				n.notify("WATCHDOG=1")
+			} else {
+				log.Printf("Warning: the activity pipeline did not respond, skipping the watchdog ping")
+			}
+		}
+	}
+}
+
+// ActivatedListener returns the control socket passed by systemd socket activation,
+// or nil if the daemon was not socket-activated
+func ActivatedListener() (net.Listener, error) {
+	pid, fds := os.Getenv("LISTEN_PID"), os.Getenv("LISTEN_FDS")
+	os.Unsetenv("LISTEN_PID")
+	os.Unsetenv("LISTEN_FDS")
+	os.Unsetenv("LISTEN_FDNAMES")
+	if fds == "" || pid != strconv.Itoa(os.Getpid()) {
+		return nil, nil
+	}
+	count, err := strconv.Atoi(fds)
+	if err != nil || count < 1 {
+		return nil, fmt.Errorf("invalid LISTEN_FDS %q", fds)
+	}
+	if count > 1 {
+		log.Printf("Warning: systemd passed %d sockets, only the first is used", count)
+	}
+
+	syscall.CloseOnExec(listenFdsStart)
+	file := os.NewFile(listenFdsStart, "LISTEN_FD_3")
+	defer file.Close()
+	listener, err := net.FileListener(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to use the socket passed by systemd: %v", err)
+	}
+	if _, ok := listener.(*net.UnixListener); !ok {
+		listener.Close()
+		return nil, fmt.Errorf("the socket passed by systemd is not a Unix stream socket")
+	}
+	return listener, nil
+}
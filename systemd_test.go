@@ -0,0 +1,196 @@
+package main
+
+import (
+	"context"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+// listenNotify binds a notify socket like systemd does and points NOTIFY_SOCKET at it
+func listenNotify(t *testing.T, watchdog time.Duration) *net.UnixConn {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "notify")
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: path, Net: "unixgram"})
+	if err != nil {
+		t.Fatalf("listen on %s: %v", path, err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	t.Setenv("NOTIFY_SOCKET", path)
+	t.Setenv("WATCHDOG_USEC", strconv.FormatInt(watchdog.Microseconds(), 10))
+	t.Setenv("WATCHDOG_PID", "")
+	return conn
+}
+
+// readNotify returns the next message sent to the notify socket, or "" if none
+// arrives within the timeout
+func readNotify(t *testing.T, conn *net.UnixConn, timeout time.Duration) string {
+	t.Helper()
+	conn.SetReadDeadline(time.Now().Add(timeout))
+	buf := make([]byte, 4096)
+	n, err := conn.Read(buf)
+	if err != nil {
+		if netErr, ok := err.(net.Error); ok && netErr.Timeout() {
+			return ""
+		}
+		t.Fatalf("read notify socket: %v", err)
+	}
+	return string(buf[:n])
+}
+
+func newTestNotifier(t *testing.T, watchdog time.Duration) (*SystemdNotifier, *net.UnixConn) {
+	t.Helper()
+	conn := listenNotify(t, watchdog)
+	n, err := NewSystemdNotifier()
+	if err != nil {
+		t.Fatalf("NewSystemdNotifier: %v", err)
+	}
+	if n == nil {
+		t.Fatal("NewSystemdNotifier returned nil with NOTIFY_SOCKET set")
+	}
+	t.Cleanup(n.Close)
+	return n, conn
+}
+
+func TestSystemdNotifierMessages(t *testing.T) {
+	n, conn := newTestNotifier(t, time.Second)
+	for _, name := range []string{"NOTIFY_SOCKET", "WATCHDOG_USEC"} {
+		if value := os.Getenv(name); value != "" {
+			t.Errorf("%s = %q after NewSystemdNotifier, want it cleared", name, value)
+		}
+	}
+	if n.watchdog != time.Second {
+		t.Errorf("watchdog = %s, want 1s", n.watchdog)
+	}
+
+	n.Ready()
+	if got := readNotify(t, conn, time.Second); got != "READY=1" {
+		t.Errorf("after Ready: got %q, want READY=1", got)
+	}
+
+	n.SetStatus("active: kitty")
+	n.SetStatus("active: kitty")
+	n.SetStatus("paused (lunch)")
+	for _, want := range []string{"STATUS=active: kitty", "STATUS=paused (lunch)"} {
+		if got := readNotify(t, conn, time.Second); got != want {
+			t.Errorf("after SetStatus: got %q, want %q", got, want)
+		}
+	}
+	if got := readNotify(t, conn, 100*time.Millisecond); got != "" {
+		t.Errorf("unchanged status was sent again: %q", got)
+	}
+
+	n.Stopping()
+	if got := readNotify(t, conn, time.Second); got != "STOPPING=1" {
+		t.Errorf("after Stopping: got %q, want STOPPING=1", got)
+	}
+}
+
+func TestSystemdNotifierWatchdog(t *testing.T) {
+	n, conn := newTestNotifier(t, 100*time.Millisecond)
+	hub := NewActivityHub()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go n.RunWatchdog(ctx, &wg, hub)
+	defer func() {
+		cancel()
+		wg.Wait()
+	}()
+
+	// The hub is not running yet, like a stuck pipeline
+	if got := readNotify(t, conn, 200*time.Millisecond); got != "" {
+		t.Errorf("watchdog pinged while the hub was stuck: %q", got)
+	}
+
+	in, out := make(chan LogEntry), make(chan LogEntry, 1)
+	go hub.Run(ctx, in, out)
+	if got := readNotify(t, conn, time.Second); got != "WATCHDOG=1" {
+		t.Errorf("with the hub running: got %q, want WATCHDOG=1", got)
+	}
+}
+
+func TestSystemdNotifierWithoutSocket(t *testing.T) {
+	t.Setenv("NOTIFY_SOCKET", "")
+	n, err := NewSystemdNotifier()
+	if n != nil || err != nil {
+		t.Fatalf("NewSystemdNotifier = %v, %v without NOTIFY_SOCKET, want nil", n, err)
+	}
+	// A nil notifier does nothing
+	n.Ready()
+	n.SetStatus("active")
+	n.Stopping()
+	n.Close()
+}
+
+func TestActivatedListenerOtherPid(t *testing.T) {
+	t.Setenv("LISTEN_PID", strconv.Itoa(os.Getpid()+1))
+	t.Setenv("LISTEN_FDS", "1")
+	listener, err := ActivatedListener()
+	if listener != nil || err != nil {
+		t.Fatalf("ActivatedListener = %v, %v for another pid, want nil", listener, err)
+	}
+	if value := os.Getenv("LISTEN_FDS"); value != "" {
+		t.Errorf("LISTEN_FDS = %q after ActivatedListener, want it cleared", value)
+	}
+}
+
+// TestActivatedListener runs itself in a child process that is passed the socket as
+// fd 3, like systemd does
+func TestActivatedListener(t *testing.T) {
+	if path := os.Getenv("HYPRTRACKER_TEST_ACTIVATED"); path != "" {
+		// The pid is only known in the child; systemd sets it before exec
+		os.Setenv("LISTEN_PID", strconv.Itoa(os.Getpid()))
+		listener, err := ActivatedListener()
+		if err != nil {
+			t.Fatalf("ActivatedListener: %v", err)
+		}
+		if listener == nil {
+			t.Fatal("ActivatedListener returned nil with LISTEN_FDS set")
+		}
+		defer listener.Close()
+		if got := listener.Addr().String(); got != path {
+			t.Errorf("listener address = %q, want %q", got, path)
+		}
+		if value := os.Getenv("LISTEN_PID"); value != "" {
+			t.Errorf("LISTEN_PID = %q after ActivatedListener, want it cleared", value)
+		}
+
+		go func() {
+			if conn, err := net.Dial("unix", path); err == nil {
+				conn.Close()
+			}
+		}()
+		conn, err := listener.Accept()
+		if err != nil {
+			t.Fatalf("accept on the passed socket: %v", err)
+		}
+		conn.Close()
+		return
+	}
+
+	path := filepath.Join(t.TempDir(), "hyprtracker.sock")
+	listener, err := net.ListenUnix("unix", &net.UnixAddr{Name: path, Net: "unix"})
+	if err != nil {
+		t.Fatalf("listen on %s: %v", path, err)
+	}
+	defer listener.Close()
+	file, err := listener.File()
+	if err != nil {
+		t.Fatalf("listener file: %v", err)
+	}
+	defer file.Close()
+
+	cmd := exec.Command(os.Args[0], "-test.run=^TestActivatedListener$", "-test.v")
+	cmd.Env = append(os.Environ(), "HYPRTRACKER_TEST_ACTIVATED="+path, "LISTEN_FDS=1")
+	cmd.ExtraFiles = []*os.File{file}
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("child process failed: %v\n%s", err, out)
+	}
+}
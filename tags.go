@@ -0,0 +1,113 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"os"
+	"path"
+	"strings"
+	"time"
+)
+
+const (
+	// TagEventType marks the start of a tag; the label is stored as the window title
+	// and an empty label clears the tag
+	TagEventType = "tag"
+	UntaggedTag  = "untagged"
+	maxTagLength = 64
+)
+
+// ValidateTag checks that a label can be sent over the socket and stored as a tag
+func ValidateTag(tag string) error {
+	if len(tag) > maxTagLength {
+		return fmt.Errorf("tag is longer than %d characters", maxTagLength)
+	}
+	if strings.ContainsAny(tag, "\r\n") {
+		return fmt.Errorf("tag must not contain line breaks")
+	}
+	return nil
+}
+
+// TagName returns the tag of an interval as shown in reports
+func (iv Interval) TagName() string {
+	if iv.Tag == "" {
+		return UntaggedTag
+	}
+	return iv.Tag
+}
+
+// MatchesTags reports whether the interval's tag matches any of the (lowercased)
+// glob patterns, where "untagged" matches intervals without a tag. An empty list
+// matches everything.
+func (iv Interval) MatchesTags(patterns []string) bool {
+	if len(patterns) == 0 {
+		return true
+	}
+	tag := strings.ToLower(iv.TagName())
+	for _, pattern := range patterns {
+		if matched, _ := path.Match(pattern, tag); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// GetActiveTag returns the tag that was active at the given time, "" if none
+func (d *Database) GetActiveTag(at time.Time) (string, error) {
+	var tag sql.NullString
+	err := d.db.QueryRow(`
+		SELECT window_title FROM events
+		WHERE event_type = ? AND timestamp <= ?
+		ORDER BY timestamp DESC, id DESC
+		LIMIT 1
+	`, TagEventType, at.Format(time.RFC3339)).Scan(&tag)
+	if err != nil && err != sql.ErrNoRows {
+		return "", fmt.Errorf("failed to read active tag: %v", err)
+	}
+	return tag.String, nil
+}
+
+// LoadActiveTag returns the tag that is currently active in the database at dbPath
+func LoadActiveTag(dbPath string) (string, error) {
+	db, err := OpenDatabase(dbPath)
+	if err != nil {
+		return "", err
+	}
+	defer db.Close()
+	return db.GetActiveTag(time.Now())
+}
+
+func generateTagsReport(db *Database, startTime, endTime time.Time, config AnalysisConfig) {
+	intervals := loadIntervals(db, startTime, endTime, config)
+	minDuration := config.TotalMinDuration()
+
+	var total time.Duration
+	for _, iv := range intervals {
+		total += iv.Duration()
+	}
+
+	tagsTable := &ReportTable{Title: "Time Spent Per Tag", Columns: []string{"Tag", "Duration", "Share %"}}
+	tagDurations := groupDurations(intervals, Interval.TagName)
+	for _, item := range SortedSummary(tagDurations, minDuration) {
+		tagsTable.AddRow(item.Name, item.Duration, float64(item.Duration)/float64(total)*100)
+	}
+
+	appsTable := &ReportTable{Title: "Time Spent Per Tag and Application", Columns: []string{"Tag", "Application", "Duration"}}
+	byTagAndApp := make(map[string]map[string]time.Duration)
+	for _, iv := range intervals {
+		if byTagAndApp[iv.TagName()] == nil {
+			byTagAndApp[iv.TagName()] = make(map[string]time.Duration)
+		}
+		byTagAndApp[iv.TagName()][iv.App] += iv.Duration()
+	}
+	for _, tag := range SortedSummary(tagDurations, minDuration) {
+		for _, item := range SortedSummary(byTagAndApp[tag.Name], minDuration) {
+			appsTable.AddRow(tag.Name, item.Name, item.Duration)
+		}
+	}
+
+	if err := RenderTables(os.Stdout, config.Format, tagsTable, appsTable); err != nil {
+		log.Fatalf("Error writing report: %v", err)
+	}
+}
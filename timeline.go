@@ -0,0 +1,386 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	timelineSlot  = 15 * time.Minute
+	ansiReset     = "\x1b[0m"
+	emptyCellText = "·"
+)
+
+// Key colors from the 256-color palette, assigned in order of total time
+var timelinePalette = []int{33, 208, 41, 199, 226, 45, 160, 129, 214, 27, 112, 205}
+
+// Intensity ramps from no activity to the busiest cell
+var (
+	heatmapColors    = []int{237, 22, 28, 34, 46}
+	heatmapPlainRamp = []string{".", ":", "+", "*", "#"}
+	weekdayLabels    = []string{"Mon", "Tue", "Wed", "Thu", "Fri", "Sat", "Sun"}
+)
+
+// IsTerminal reports whether f is connected to a terminal
+func IsTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
+}
+
+// UseColor reports whether ANSI colors should be written to stdout
+func UseColor() bool {
+	return IsTerminal(os.Stdout) && os.Getenv("NO_COLOR") == ""
+}
+
+func colorize(text string, color int, enabled bool) string {
+	if !enabled {
+		return text
+	}
+	return fmt.Sprintf("\x1b[38;5;%dm%s%s", color, text, ansiReset)
+}
+
+// weekdayIndex returns the day of the week with Monday as 0
+func weekdayIndex(t time.Time) int {
+	return (int(t.Weekday()) + 6) % 7
+}
+
+// intensityLevel maps value onto 0..levels-1, where 0 means no activity at all
+func intensityLevel(value, peak time.Duration, levels int) int {
+	if value <= 0 || peak <= 0 {
+		return 0
+	}
+	level := int((value*time.Duration(levels-1) + peak - 1) / peak)
+	if level < 1 {
+		level = 1
+	}
+	if level > levels-1 {
+		level = levels - 1
+	}
+	return level
+}
+
+// clipIntervals returns the parts of the intervals that fall within [from, to)
+func clipIntervals(intervals []Interval, from, to time.Time) []Interval {
+	var clipped []Interval
+	for _, iv := range intervals {
+		if !iv.End.After(from) || !iv.Start.Before(to) {
+			continue
+		}
+		if iv.Start.Before(from) {
+			iv.Start = from
+		}
+		if iv.End.After(to) {
+			iv.End = to
+		}
+		clipped = append(clipped, iv)
+	}
+	return clipped
+}
+
+// ParseDay parses a YYYY-MM-DD date in local time; an empty string means today
+func ParseDay(day string) (time.Time, error) {
+	if day == "" {
+		return BucketStart(time.Now(), BucketDay), nil
+	}
+	t, err := time.ParseInLocation("2006-01-02", day, time.Local)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid date %q (expected YYYY-MM-DD): %v", day, err)
+	}
+	return t, nil
+}
+
+func generateTimelineReport(db *Database, config AnalysisConfig) {
+	dayStart, err := ParseDay(config.Date)
+	if err != nil {
+		log.Fatalf("Error: %v", err)
+	}
+	dayEnd := NextBucket(dayStart, BucketDay)
+
+	intervals := clipIntervals(loadIntervals(db, dayStart, dayEnd, config), dayStart, dayEnd)
+	keyFn := config.Config.GroupKeyFunc(config.GroupBy)
+
+	if config.Format != FormatText {
+		table := &ReportTable{
+			Title:   fmt.Sprintf("Timeline for %s", dayStart.Format("Mon 2006-01-02")),
+			Columns: []string{"Start", "End", "Activity", "Duration"},
+		}
+		for _, iv := range intervals {
+			table.AddRow(iv.Start, iv.End, keyFn(iv), iv.Duration())
+		}
+		if err := RenderTables(os.Stdout, config.Format, table); err != nil {
+			log.Fatalf("Error writing report: %v", err)
+		}
+		return
+	}
+
+	RenderTimeline(os.Stdout, intervals, dayStart, dayEnd, keyFn, UseColor())
+}
+
+// RenderTimeline draws a day as a strip of 15 minute slots, each showing the activity
+// that occupied most of the slot, followed by a legend with per-activity totals
+func RenderTimeline(w io.Writer, intervals []Interval, dayStart, dayEnd time.Time, keyFn func(Interval) string, color bool) {
+	fmt.Fprintf(w, "\n--- Timeline for %s ---\n", dayStart.Format("Mon 2006-01-02"))
+	if len(intervals) == 0 {
+		fmt.Fprintln(w, "No activity recorded for this day.")
+		return
+	}
+
+	totals := make(map[string]time.Duration)
+	var slots []map[string]time.Duration
+	for slotStart := dayStart; slotStart.Before(dayEnd); slotStart = slotStart.Add(timelineSlot) {
+		slot := make(map[string]time.Duration)
+		for _, iv := range clipIntervals(intervals, slotStart, slotStart.Add(timelineSlot)) {
+			slot[keyFn(iv)] += iv.Duration()
+		}
+		slots = append(slots, slot)
+	}
+	for _, iv := range intervals {
+		totals[keyFn(iv)] += iv.Duration()
+	}
+
+	keys := make([]string, 0, len(totals))
+	for key := range totals {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if totals[keys[i]] != totals[keys[j]] {
+			return totals[keys[i]] > totals[keys[j]]
+		}
+		return keys[i] < keys[j]
+	})
+
+	// Beyond the palette (or the alphabet in plain mode) activities share the last style
+	styles := make(map[string]int, len(keys))
+	for i, key := range keys {
+		styles[key] = min(i, len(timelinePalette)-1)
+	}
+	symbol := func(key string, full bool) string {
+		if color {
+			block := "▒"
+			if full {
+				block = "█"
+			}
+			return colorize(block, timelinePalette[styles[key]], true)
+		}
+		letter := string(rune('A' + min(styles[key], 25)))
+		if !full {
+			letter = strings.ToLower(letter)
+		}
+		return letter
+	}
+
+	// Hour axis, one label every three hours
+	slotsPerHour := int(time.Hour / timelineSlot)
+	var axis strings.Builder
+	for i := range slots {
+		if i%(3*slotsPerHour) == 0 {
+			label := fmt.Sprintf("%02d", i/slotsPerHour)
+			axis.WriteString(label)
+		} else if i%(3*slotsPerHour) >= 2 {
+			axis.WriteString(" ")
+		}
+	}
+	fmt.Fprintln(w, axis.String())
+
+	var strip strings.Builder
+	for _, slot := range slots {
+		var best string
+		var bestDuration, covered time.Duration
+		for key, d := range slot {
+			covered += d
+			if d > bestDuration || (d == bestDuration && key < best) {
+				best, bestDuration = key, d
+			}
+		}
+		if covered == 0 {
+			strip.WriteString(emptyCellText)
+			continue
+		}
+		strip.WriteString(symbol(best, covered*2 >= timelineSlot))
+	}
+	fmt.Fprintln(w, strip.String())
+
+	fmt.Fprintln(w)
+	for _, key := range keys {
+		fmt.Fprintf(w, "%s %-40s %s\n", symbol(key, true), key, FormatDuration(totals[key]))
+	}
+	if !color {
+		fmt.Fprintf(w, "(uppercase: activity for at least half of the %s slot, lowercase: less)\n", FormatDuration(timelineSlot))
+	}
+}
+
+func generateHeatmapReport(db *Database, startTime, endTime time.Time, config AnalysisConfig) {
+	intervals := loadIntervals(db, startTime, endTime, config)
+
+	var grid [7][24]time.Duration
+	for _, piece := range SplitIntervalsByBucket(intervals, BucketHour) {
+		grid[weekdayIndex(piece.Bucket)][piece.Bucket.Hour()] += piece.Duration()
+	}
+
+	if config.Format != FormatText {
+		table := &ReportTable{Title: "Activity By Weekday And Hour", Columns: []string{"Day"}}
+		for hour := 0; hour < 24; hour++ {
+			table.Columns = append(table.Columns, fmt.Sprintf("%02d", hour))
+		}
+		for day := range grid {
+			row := []any{weekdayLabels[day]}
+			for hour := range grid[day] {
+				row = append(row, grid[day][hour])
+			}
+			table.AddRow(row...)
+		}
+		if err := RenderTables(os.Stdout, config.Format, table); err != nil {
+			log.Fatalf("Error writing report: %v", err)
+		}
+		return
+	}
+
+	RenderHourHeatmap(os.Stdout, grid, UseColor())
+}
+
+// RenderHourHeatmap draws a weekday × hour-of-day grid shaded by total active time
+func RenderHourHeatmap(w io.Writer, grid [7][24]time.Duration, color bool) {
+	fmt.Fprintln(w, "\n--- Activity By Weekday And Hour ---")
+
+	var busiest time.Duration
+	for day := range grid {
+		for hour := range grid[day] {
+			busiest = max(busiest, grid[day][hour])
+		}
+	}
+	if busiest == 0 {
+		fmt.Fprintln(w, "No activity recorded in this time range.")
+		return
+	}
+
+	var header strings.Builder
+	header.WriteString("    ")
+	for hour := 0; hour < 24; hour++ {
+		fmt.Fprintf(&header, "%02d ", hour)
+	}
+	fmt.Fprintln(w, strings.TrimRight(header.String(), " "))
+
+	for day := range grid {
+		var line strings.Builder
+		line.WriteString(weekdayLabels[day] + " ")
+		for hour := range grid[day] {
+			level := intensityLevel(grid[day][hour], busiest, len(heatmapColors))
+			line.WriteString(heatCell(level, color, 2) + " ")
+		}
+		fmt.Fprintln(w, strings.TrimRight(line.String(), " "))
+	}
+
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, heatLegend(color)+fmt.Sprintf("  (busiest hour: %s)", FormatDuration(busiest)))
+}
+
+func generateCalendarReport(db *Database, config AnalysisConfig) {
+	today := BucketStart(time.Now(), BucketDay)
+	// 53 week columns ending with the current week, like a contribution graph
+	firstWeek := BucketStart(today, BucketWeek).AddDate(0, 0, -52*7)
+	end := NextBucket(today, BucketDay)
+
+	intervals := loadIntervals(db, firstWeek, end, config)
+	days := make(map[time.Time]time.Duration)
+	for _, piece := range SplitIntervalsByBucket(intervals, BucketDay) {
+		days[piece.Bucket] += piece.Duration()
+	}
+
+	if config.Format != FormatText {
+		table := &ReportTable{Title: "Daily Activity", Columns: []string{"Date", "Duration"}}
+		for day := firstWeek; day.Before(end); day = NextBucket(day, BucketDay) {
+			table.AddRow(day.Format("2006-01-02"), days[day])
+		}
+		if err := RenderTables(os.Stdout, config.Format, table); err != nil {
+			log.Fatalf("Error writing report: %v", err)
+		}
+		return
+	}
+
+	RenderCalendarHeatmap(os.Stdout, days, firstWeek, today, UseColor())
+}
+
+// RenderCalendarHeatmap draws one column per week and one row per weekday, shaded by daily active time
+func RenderCalendarHeatmap(w io.Writer, days map[time.Time]time.Duration, firstWeek, today time.Time, color bool) {
+	fmt.Fprintln(w, "\n--- Daily Activity Over The Last Year ---")
+
+	var busiest, total time.Duration
+	activeDays := 0
+	for _, d := range days {
+		busiest = max(busiest, d)
+		total += d
+		if d > 0 {
+			activeDays++
+		}
+	}
+
+	var weeks []time.Time
+	for week := firstWeek; !week.After(today); week = NextBucket(week, BucketWeek) {
+		weeks = append(weeks, week)
+	}
+
+	// Month labels above the first week that starts in each month
+	monthRow := []rune(strings.Repeat(" ", 4+2*len(weeks)))
+	lastMonth := time.Month(0)
+	for i, week := range weeks {
+		if week.Month() != lastMonth {
+			lastMonth = week.Month()
+			label := week.Format("Jan")
+			pos := 4 + 2*i
+			if pos+len(label) <= len(monthRow) {
+				copy(monthRow[pos:], []rune(label))
+			}
+		}
+	}
+	fmt.Fprintln(w, strings.TrimRight(string(monthRow), " "))
+
+	for weekday := 0; weekday < 7; weekday++ {
+		var line strings.Builder
+		line.WriteString(weekdayLabels[weekday] + " ")
+		for _, week := range weeks {
+			day := week.AddDate(0, 0, weekday)
+			if day.After(today) {
+				break
+			}
+			level := intensityLevel(days[day], busiest, len(heatmapColors))
+			line.WriteString(heatCell(level, color, 1) + " ")
+		}
+		fmt.Fprintln(w, strings.TrimRight(line.String(), " "))
+	}
+
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, heatLegend(color))
+	fmt.Fprintf(w, "Active days: %d, total: %s, busiest day: %s\n", activeDays, FormatDuration(total), FormatDuration(busiest))
+}
+
+func heatCell(level int, color bool, width int) string {
+	if !color {
+		return strings.Repeat(heatmapPlainRamp[level], width)
+	}
+	if level == 0 {
+		return colorize(strings.Repeat(emptyCellText, width), heatmapColors[0], true)
+	}
+	return colorize(strings.Repeat("█", width), heatmapColors[level], true)
+}
+
+func heatLegend(color bool) string {
+	var legend strings.Builder
+	legend.WriteString("Less ")
+	for level := range heatmapColors {
+		if color {
+			legend.WriteString(heatCell(level, true, 1))
+		} else {
+			legend.WriteString(heatmapPlainRamp[level])
+		}
+	}
+	legend.WriteString(" More")
+	return legend.String()
+}
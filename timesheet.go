@@ -0,0 +1,238 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ProjectRule assigns intervals to a billable project. Patterns are matched like
+// category patterns, and may also be prefixed with "category:" or "tag:" to match
+// the category or tag of an interval. The first matching project wins.
+type ProjectRule struct {
+	Name   string   `json:"name"`
+	Client string   `json:"client"`
+	Rate   float64  `json:"rate"`
+	Match  []string `json:"match"`
+}
+
+// TimesheetConfig holds the rounding rules of the timesheet report
+type TimesheetConfig struct {
+	// Rounding is the billing increment, e.g. "15m"; empty bills exact time
+	Rounding string `json:"rounding"`
+	// RoundPer is "day" to round each project's time per day, or "total" to round
+	// each project's total only
+	RoundPer string `json:"round_per"`
+	// RoundMode is "up", "nearest" or "down"
+	RoundMode string `json:"round_mode"`
+	Currency  string `json:"currency"`
+
+	increment time.Duration
+}
+
+// validate checks the rounding rules and fills in the defaults
+func (t *TimesheetConfig) validate() error {
+	if t.Rounding != "" {
+		increment, err := time.ParseDuration(t.Rounding)
+		if err != nil || increment <= 0 {
+			return fmt.Errorf("invalid timesheet rounding %q (expected a duration such as \"15m\")", t.Rounding)
+		}
+		t.increment = increment
+	}
+	switch t.RoundPer {
+	case "":
+		t.RoundPer = "day"
+	case "day", "total":
+	default:
+		return fmt.Errorf("invalid timesheet round_per %q (must be 'day' or 'total')", t.RoundPer)
+	}
+	switch t.RoundMode {
+	case "":
+		t.RoundMode = "up"
+	case "up", "nearest", "down":
+	default:
+		return fmt.Errorf("invalid timesheet round_mode %q (must be 'up', 'nearest' or 'down')", t.RoundMode)
+	}
+	return nil
+}
+
+// Round applies the rounding increment and mode to d
+func (t TimesheetConfig) Round(d time.Duration) time.Duration {
+	if t.increment <= 0 || d <= 0 {
+		return d
+	}
+	switch t.RoundMode {
+	case "down":
+		return d.Truncate(t.increment)
+	case "nearest":
+		return d.Round(t.increment)
+	default:
+		if rounded := d.Truncate(t.increment); rounded < d {
+			return rounded + t.increment
+		}
+		return d
+	}
+}
+
+// Describe returns the rounding rules in words, for report titles
+func (t TimesheetConfig) Describe() string {
+	if t.increment <= 0 {
+		return "exact time"
+	}
+	per := "each day"
+	if t.RoundPer == "total" {
+		per = "the total"
+	}
+	return fmt.Sprintf("%s rounded %s to %s", per, t.RoundMode, FormatDuration(t.increment))
+}
+
+// MatchIntervalPattern matches a project pattern against an interval
+func (c *Config) MatchIntervalPattern(pattern string, iv Interval) bool {
+	switch {
+	case strings.HasPrefix(pattern, "category:"):
+		matched, _ := path.Match(strings.ToLower(strings.TrimPrefix(pattern, "category:")), strings.ToLower(c.CategorizeInterval(iv)))
+		return matched
+	case strings.HasPrefix(pattern, "tag:"):
+		matched, _ := path.Match(strings.ToLower(strings.TrimPrefix(pattern, "tag:")), strings.ToLower(iv.TagName()))
+		return matched
+	default:
+		return MatchWindowPattern(pattern, iv.App, iv.Title)
+	}
+}
+
+// Project returns the first project matching the interval, or nil if it is not billable
+func (c *Config) Project(iv Interval) *ProjectRule {
+	if c == nil {
+		return nil
+	}
+	for i, project := range c.Projects {
+		for _, pattern := range project.Match {
+			if c.MatchIntervalPattern(pattern, iv) {
+				return &c.Projects[i]
+			}
+		}
+	}
+	return nil
+}
+
+// TimesheetLine is the time spent on one project, either on one day or in total
+type TimesheetLine struct {
+	Day     time.Time
+	Client  string
+	Project string
+	Rate    float64
+	Tracked time.Duration
+	Billed  time.Duration
+}
+
+func (l TimesheetLine) Hours() float64 {
+	return DecimalHours(l.Billed)
+}
+
+// Amount is the billed time times the hourly rate, rounded to cents
+func (l TimesheetLine) Amount() float64 {
+	return math.Round(l.Billed.Hours()*l.Rate*100) / 100
+}
+
+// BuildTimesheet sums the billable time per day and project, and per project in total,
+// applying the rounding rules either to each day or to the totals
+func BuildTimesheet(intervals []Interval, config *Config) ([]TimesheetLine, []TimesheetLine) {
+	type lineKey struct {
+		day     time.Time
+		project *ProjectRule
+	}
+	tracked := make(map[lineKey]time.Duration)
+	for _, piece := range SplitIntervalsByBucket(intervals, BucketDay) {
+		if project := config.Project(piece.Interval); project != nil {
+			tracked[lineKey{piece.Bucket, project}] += piece.Duration()
+		}
+	}
+
+	rules := config.Timesheet
+	var days []TimesheetLine
+	totals := make(map[*ProjectRule]*TimesheetLine)
+	for key, d := range tracked {
+		line := TimesheetLine{Day: key.day, Client: key.project.Client, Project: key.project.Name, Rate: key.project.Rate, Tracked: d, Billed: d}
+		if rules.RoundPer == "day" {
+			line.Billed = rules.Round(d)
+		}
+		days = append(days, line)
+
+		total, ok := totals[key.project]
+		if !ok {
+			total = &TimesheetLine{Client: line.Client, Project: line.Project, Rate: line.Rate}
+			totals[key.project] = total
+		}
+		total.Tracked += line.Tracked
+		total.Billed += line.Billed
+	}
+
+	var projects []TimesheetLine
+	for _, total := range totals {
+		if rules.RoundPer == "total" {
+			total.Billed = rules.Round(total.Tracked)
+		}
+		projects = append(projects, *total)
+	}
+
+	less := func(a, b TimesheetLine) bool {
+		if !a.Day.Equal(b.Day) {
+			return a.Day.Before(b.Day)
+		}
+		if a.Client != b.Client {
+			return a.Client < b.Client
+		}
+		return a.Project < b.Project
+	}
+	sort.Slice(days, func(i, j int) bool { return less(days[i], days[j]) })
+	sort.Slice(projects, func(i, j int) bool { return less(projects[i], projects[j]) })
+	return days, projects
+}
+
+func generateTimesheetReport(db *Database, startTime, endTime time.Time, config AnalysisConfig) {
+	if config.Config == nil || len(config.Config.Projects) == 0 {
+		log.Fatalf("Error: the timesheet report needs a \"projects\" section in the configuration file")
+	}
+
+	intervals := loadIntervals(db, startTime, endTime, config)
+	days, projects := BuildTimesheet(intervals, config.Config)
+	rules := config.Config.Timesheet
+
+	amountHeader := "Amount"
+	if rules.Currency != "" {
+		amountHeader += " (" + rules.Currency + ")"
+	}
+
+	daysTable := &ReportTable{
+		Title:   fmt.Sprintf("Timesheet (%s)", rules.Describe()),
+		Columns: []string{"Date", "Client", "Project", "Tracked", "Billed", "Hours", "Rate", amountHeader},
+	}
+	for _, line := range days {
+		daysTable.AddRow(line.Day.Format("2006-01-02"), line.Client, line.Project, line.Tracked, line.Billed, line.Hours(), line.Rate, line.Amount())
+	}
+
+	totalsTable := &ReportTable{
+		Title:   "Totals Per Client and Project",
+		Columns: []string{"Client", "Project", "Tracked", "Billed", "Hours", "Rate", amountHeader},
+	}
+	var grand TimesheetLine
+	var grandAmount float64
+	for _, line := range projects {
+		totalsTable.AddRow(line.Client, line.Project, line.Tracked, line.Billed, line.Hours(), line.Rate, line.Amount())
+		grand.Tracked += line.Tracked
+		grand.Billed += line.Billed
+		grandAmount += line.Amount()
+	}
+	if len(projects) > 0 {
+		totalsTable.AddRow("Total", "", grand.Tracked, grand.Billed, grand.Hours(), "", math.Round(grandAmount*100)/100)
+	}
+
+	if err := RenderTables(os.Stdout, config.Format, daysTable, totalsTable); err != nil {
+		log.Fatalf("Error writing report: %v", err)
+	}
+}
@@ -2,6 +2,7 @@ package main
 
 import (
 	"fmt"
+	"math"
 	"time"
 
 	"slices"
@@ -13,7 +14,6 @@ const (
 	DebounceTime            = 3 * time.Second
 	DefaultGeneralDebounceTime = 500 * time.Millisecond
 	DefaultIdleThreshold     = 15 * time.Minute
-	SocketPath              = "/tmp/hyprtracker.sock"
 )
 
 
@@ -49,6 +49,30 @@ type LoggerConfig struct {
 	GeneralDebounceTime    time.Duration
 	EnableSystray          bool
 	DBPath                 string
+	Config                 *Config
+	// Replace asks a daemon already running to shut down instead of refusing to start
+	Replace                bool
+}
+
+type AnalysisConfig struct {
+	DBPath      string
+	Keywords    []string
+	MinDuration time.Duration
+	AppOnly     bool
+	TimeRange   string
+	Report      string
+	Bucket      BucketUnit
+	Format      string
+	GroupBy     string
+	Date        string
+	Compare     string
+	Config      *Config
+	TagFilter   []string
+
+	// MinDurationPerVisit applies MinDuration to each visit instead of aggregated totals
+	MinDurationPerVisit bool
+	BreakThreshold      time.Duration
+	DeepWork            DeepWorkRules
 }
 
 func IsTerminalEmulator(windowName string) bool {
@@ -72,4 +96,11 @@ func FormatDuration(d time.Duration) string {
 	return fmt.Sprintf("%ds", s)
 }
 
+// DecimalHours converts d to hours rounded to two decimals, as used on timesheets
+func DecimalHours(d time.Duration) float64 {
+	return math.Round(d.Hours()*100) / 100
+}
+
 var DefaultDBPath = GetDefaultDBPath()
+
+var SocketPath = GetSocketPath()